@@ -3,11 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/ksred/ccswitch/internal/errors"
-	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
 	"github.com/ksred/ccswitch/internal/utils"
 	"github.com/spf13/cobra"
@@ -18,50 +15,39 @@ func newCheckoutCmd() *cobra.Command {
 		Use:   "checkout <branch>",
 		Short: "Checkout an existing branch into a new worktree",
 		Args:  cobra.ExactArgs(1),
-		Run:   checkoutSession,
+		RunE:  checkoutSession,
 	}
 }
 
-func checkoutSession(cmd *cobra.Command, args []string) {
+func checkoutSession(cmd *cobra.Command, args []string) error {
 	branchName := strings.TrimSpace(args[0])
 
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Create session manager
-	manager := session.NewManager(currentDir)
+	manager := newManager(cmd, currentDir)
 
-	// Checkout the session
-	if err := manager.CheckoutSession(branchName); err != nil {
-		ui.Errorf("✗ %s", err)
-
-		// Provide helpful tips based on error
-		hint := errors.ErrorHint(err)
-		if hint != "" {
-			ui.Infof("  Tip: %s", hint)
-		}
+	autoFetch(manager, branchName)
 
-		return
+	// Checkout the session
+	result, err := manager.CheckoutSession(branchName)
+	if err != nil {
+		return err
 	}
 
 	// Success!
-	sessionName := utils.Slugify(branchName)
-	repoName := filepath.Base(currentDir)
-
-	// Get the full worktree path
-	homeDir, _ := os.UserHomeDir()
-	worktreePath := filepath.Join(homeDir, ".ccswitch", "worktrees", repoName, sessionName)
-
-	ui.Successf("✓ Checked out session: %s", sessionName)
-	ui.Infof("Branch: %s", branchName)
-	ui.Infof("Location: ~/.ccswitch/worktrees/%s/%s", repoName, sessionName)
+	checkedOut := result.Session
+	ui.Successf("✓ Checked out session: %s", checkedOut.Name)
+	ui.Infof("Branch: %s", checkedOut.Branch)
+	ui.Infof("Location: %s", displayPath(checkedOut.Path))
+	printCarriedOver(result.CarriedOver)
 
 	// Output the cd command for the shell wrapper to execute on a separate line
-	fmt.Printf("\ncd %s\n", worktreePath)
+	printCd(manager, checkedOut.Path)
 
 	// If shell integration is not active, show a helpful message
 	if !utils.IsShellIntegrationActive() {
@@ -69,4 +55,6 @@ func checkoutSession(cmd *cobra.Command, args []string) {
 		ui.Infof("💡 Note: Shell integration is not active.")
 		ui.Info(utils.GetShellIntegrationInstructions())
 	}
+
+	return nil
 }
@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/ksred/ccswitch/internal/errors"
 	"github.com/ksred/ccswitch/internal/git"
 	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,50 +24,87 @@ func newCleanupCmd() *cobra.Command {
 
 Without arguments: Shows an interactive list of sessions to cleanup
 With session name: Removes the specified session
-With --all flag: Removes all worktrees except main/master (bulk cleanup)
+With --all flag: Removes all worktrees except the main repository and
+protected branches (bulk cleanup)
+With --merged, --gone or --stale: Removes only the sessions they select,
+after showing a preview. Combined, they select sessions matching any of
+them. Sessions with uncommitted changes are never selected. Branches of
+merged sessions are deleted; other branches are kept.
+
+The default branch and branches matching git.protected_branches are never
+deleted, and --all, --merged, --gone and --stale leave their sessions alone.
+
+Cleanup refuses to throw away uncommitted changes, untracked files, or,
+when deleting a branch, commits that are on no other branch, remote branch
+or tag. It shows what would be lost; for a single session, typing its name
+removes it anyway. --force skips the check.
+
+Removed sessions go to the trash, uncommitted changes included, and
+'ccswitch undo' brings back the last one; see 'ccswitch trash'.
 
 Examples:
   ccswitch cleanup                  # Interactive selection
   ccswitch cleanup my-feature       # Remove specific session
-  ccswitch cleanup --all            # Remove all worktrees (with confirmation)`,
+  ccswitch cleanup --all            # Remove all worktrees (with confirmation)
+  ccswitch cleanup --merged         # Remove sessions whose work has landed
+  ccswitch cleanup --gone           # Remove sessions whose upstream was deleted
+  ccswitch cleanup --stale 30d      # Remove sessions untouched for 30 days
+  ccswitch cleanup my-feature -f    # Remove it even if that loses work`,
 		Args: cobra.MaximumNArgs(1),
-		Run:  cleanupSession,
+		RunE: cleanupSession,
 	}
 
-	cmd.Flags().Bool("all", false, "Remove ALL worktrees except main/master (bulk cleanup)")
+	cmd.Flags().Bool("all", false, "Remove ALL worktrees except the main repository and protected branches (bulk cleanup)")
+	cmd.Flags().Bool("merged", false, "Remove sessions merged into the default branch, including squash merges")
+	cmd.Flags().Bool("gone", false, "Remove sessions whose upstream branch was deleted")
+	cmd.Flags().String("stale", "", "Remove sessions without commits or switches for this long (e.g. 30d, 2w)")
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	cmd.Flags().Bool("no-pr", false, "Don't look up pull requests when checking for merges")
+	cmd.Flags().BoolP("force", "f", false, "Remove sessions even if that loses uncommitted changes or unpushed commits")
+	cmd.MarkFlagsMutuallyExclusive("all", "merged")
+	cmd.MarkFlagsMutuallyExclusive("all", "gone")
+	cmd.MarkFlagsMutuallyExclusive("all", "stale")
 
 	return cmd
 }
 
-func cleanupSession(cmd *cobra.Command, args []string) {
+func cleanupSession(cmd *cobra.Command, args []string) error {
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Create session manager
-	manager := session.NewManager(currentDir)
+	manager := newManager(cmd, currentDir)
 
 	// Get sessions
 	sessions, err := manager.ListSessions()
 	if err != nil {
-		ui.Errorf("✗ Failed to list sessions: %v", err)
-		return
+		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
 	if len(sessions) == 0 {
 		ui.Info("No active sessions to cleanup")
-		return
+		return nil
 	}
 
 	// Check if --all flag is set
 	cleanupAll, _ := cmd.Flags().GetBool("all")
+	force, _ := cmd.Flags().GetBool("force")
 
 	if cleanupAll {
-		cleanupAllSessions(manager, sessions)
-		return
+		return cleanupAllSessions(cmd, manager, sessions, force)
+	}
+
+	merged, _ := cmd.Flags().GetBool("merged")
+	gone, _ := cmd.Flags().GetBool("gone")
+	stale, _ := cmd.Flags().GetString("stale")
+	if merged || gone || stale != "" {
+		if len(args) > 0 {
+			return errors.Usage(fmt.Errorf("give either a session name or --merged/--gone/--stale, not both"))
+		}
+		return cleanupSelectedSessions(cmd, manager, sessions)
 	}
 
 	var sessionName string
@@ -84,19 +124,18 @@ func cleanupSession(cmd *cobra.Command, args []string) {
 
 		scanner := bufio.NewScanner(os.Stdin)
 		if !scanner.Scan() {
-			return
+			return nil
 		}
 
 		input := strings.TrimSpace(scanner.Text())
 		if input == "q" || input == "" {
-			return
+			return nil
 		}
 
 		// Parse number
 		var choice int
 		if _, err := fmt.Sscanf(input, "%d", &choice); err != nil || choice < 1 || choice > len(sessions) {
-			ui.Error("✗ Invalid selection")
-			return
+			return fmt.Errorf("invalid selection %q", input)
 		}
 
 		sessionName = sessions[choice-1].Name
@@ -113,40 +152,60 @@ func cleanupSession(cmd *cobra.Command, args []string) {
 	}
 
 	if targetSession == nil {
-		ui.Errorf("✗ Session not found: %s", sessionName)
-		return
+		return errors.Wrap(errors.ErrSessionNotFound, sessionName)
 	}
 
-	// Ask about branch deletion
-	fmt.Printf("Delete branch %s? (y/N): ", targetSession.Branch)
+	// Ask about branch deletion, unless the branch is one to keep
 	scanner := bufio.NewScanner(os.Stdin)
 	deleteBranch := false
-	if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
-		deleteBranch = true
+	if manager.IsProtected(targetSession.Branch) {
+		ui.Infof("Keeping protected branch %s", targetSession.Branch)
+	} else {
+		fmt.Printf("Delete branch %s? (y/N): ", targetSession.Branch)
+		if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
+			deleteBranch = true
+		}
+	}
+
+	if !force {
+		loss, err := manager.CheckRemoval(targetSession.Path, targetSession.Branch, deleteBranch)
+		if err != nil {
+			return errors.Wrap(err, "failed to check for unsaved work")
+		}
+		if !loss.Empty() {
+			ui.Warningf("⚠️  Removing %s would lose %s:", targetSession.Name, loss)
+			printLoss(loss)
+			fmt.Println()
+			if !confirmLoss(scanner, targetSession.Name) {
+				return fmt.Errorf("%w: nothing removed, %s has work that exists nowhere else", errors.ErrUncommittedChanges, targetSession.Name)
+			}
+			force = true
+		}
 	}
 
 	// Remove the session
-	if err := manager.RemoveSession(targetSession.Path, deleteBranch, targetSession.Branch); err != nil {
-		ui.Errorf("✗ Failed to cleanup session: %v", err)
-		return
+	opts := session.RemoveOptions{Branch: targetSession.Branch, DeleteBranch: deleteBranch, Force: force}
+	if err := manager.RemoveSession(targetSession.Path, opts); err != nil {
+		return errors.Wrap(err, "failed to cleanup session")
 	}
 
 	ui.Successf("✓ Cleaned up session: %s", sessionName)
+	ui.Info("Run 'ccswitch undo' to restore it")
+	return nil
 }
 
-func cleanupAllSessions(manager *session.Manager, sessions []git.SessionInfo) {
-	// Filter out the main session and any session on main/master branch
+func cleanupAllSessions(cmd *cobra.Command, manager *session.Manager, sessions []git.SessionInfo, force bool) error {
+	// Leave the main repository and any worktree on a protected branch alone
 	var worktreeSessions []git.SessionInfo
 	for _, s := range sessions {
-		// Skip the main session (primary repository) and any worktree on main/master branch
-		if s.Name != "main" && s.Branch != "main" && s.Branch != "master" {
+		if !manager.IsMainRepo(s) && !manager.IsProtected(s.Branch) {
 			worktreeSessions = append(worktreeSessions, s)
 		}
 	}
 
 	if len(worktreeSessions) == 0 {
 		ui.Info("No worktree sessions to cleanup")
-		return
+		return nil
 	}
 
 	// Show what will be deleted
@@ -172,42 +231,216 @@ func cleanupAllSessions(manager *session.Manager, sessions []git.SessionInfo) {
 
 	fmt.Println()
 
-	// Remove each session
-	successCount := 0
-	for _, session := range worktreeSessions {
-		if err := manager.RemoveSession(session.Path, deleteBranches, session.Branch); err != nil {
-			ui.Errorf("✗ Failed to remove %s: %v", session.Name, err)
-		} else {
-			ui.Successf("✓ Successfully removed: %s", session.Name)
-			successCount++
-		}
-	}
+	successCount, err := removeSessions(manager, worktreeSessions, func(git.SessionInfo) bool { return deleteBranches }, force)
 
 	// Summary
 	fmt.Println()
-	if successCount == len(worktreeSessions) {
+	if manager.IsDryRun() {
+		ui.Infof("Would remove %d out of %d worktrees", successCount, len(worktreeSessions))
+	} else if successCount == len(worktreeSessions) {
 		ui.Successf("✅ All %d worktrees removed successfully!", successCount)
 	} else {
 		ui.Infof("Removed %d out of %d worktrees", successCount, len(worktreeSessions))
 	}
 
-	// Switch to main/master branch
-	switchToMainBranch()
+	switchToDefaultBranch(cmd, manager)
+	return err
 }
 
-func switchToMainBranch() {
-	// Try to switch to main first, then master if main doesn't exist
-	branches := []string{"main", "master"}
+// cleanupCandidate is a session picked by --merged, --gone or --stale
+type cleanupCandidate struct {
+	report  session.Report
+	reasons []string
+}
 
-	for _, branch := range branches {
-		cmd := exec.Command("git", "checkout", branch)
-		_, err := cmd.CombinedOutput()
-		if err == nil {
-			ui.Successf("✓ Switched to %s branch", branch)
-			return
+func cleanupSelectedSessions(cmd *cobra.Command, manager *session.Manager, sessions []git.SessionInfo) error {
+	merged, _ := cmd.Flags().GetBool("merged")
+	gone, _ := cmd.Flags().GetBool("gone")
+	staleFlag, _ := cmd.Flags().GetString("stale")
+	yes, _ := cmd.Flags().GetBool("yes")
+	force, _ := cmd.Flags().GetBool("force")
+	noPR, _ := cmd.Flags().GetBool("no-pr")
+
+	var staleAfter time.Duration
+	if staleFlag != "" {
+		var err error
+		if staleAfter, err = utils.ParseAge(staleFlag); err != nil {
+			return errors.Usage(err)
+		}
+	}
+
+	var worktrees []git.SessionInfo
+	for _, s := range sessions {
+		if !manager.IsMainRepo(s) && !manager.IsProtected(s.Branch) {
+			worktrees = append(worktrees, s)
 		}
 	}
+	reports := manager.Reports(worktrees, session.ReportOptions{PullRequests: merged && !noPR})
+
+	var candidates []cleanupCandidate
+	var dirty []string
+	for _, r := range reports {
+		var reasons []string
+		if merged && r.Merged() {
+			reasons = append(reasons, "merged")
+		}
+		if gone && r.Session.Status != nil && r.Session.Status.UpstreamGone {
+			reasons = append(reasons, "upstream gone")
+		}
+		if staleAfter > 0 && r.Stale(staleAfter) {
+			reasons = append(reasons, "stale")
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		// Removing these would throw away work that exists nowhere else
+		if r.Session.Status == nil || r.Session.Status.Dirty() {
+			dirty = append(dirty, r.Session.Name)
+			continue
+		}
+		candidates = append(candidates, cleanupCandidate{report: r, reasons: reasons})
+	}
 
-	// If we couldn't switch to main or master, just inform the user
-	ui.Info("ℹ Could not switch to main/master branch")
+	if len(dirty) > 0 {
+		ui.Warningf("⚠️  Skipping sessions with uncommitted changes: %s", strings.Join(dirty, ", "))
+	}
+	if len(candidates) == 0 {
+		ui.Info("No sessions to cleanup")
+		return nil
+	}
+
+	// Preview
+	ui.Title("🧹 These sessions will be removed:")
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tBRANCH\tREASON\tACTIVE")
+	for _, c := range candidates {
+		branch := c.report.Session.Branch
+		if !c.report.Merged() {
+			branch += " (kept)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.report.Session.Name, branch, strings.Join(c.reasons, ", "), orDash(ui.FormatAge(c.report.LastActivity())))
+	}
+	w.Flush()
+	fmt.Println()
+
+	if !yes {
+		if !utils.IsInteractive() {
+			return errors.Usage(fmt.Errorf("refusing to remove sessions without confirmation; pass --yes to skip it"))
+		}
+		fmt.Printf("Remove %d session(s)? (y/N): ", len(candidates))
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() || strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			ui.Info("Nothing removed")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	// Only branches whose work is on the default branch are safe to delete
+	mergedBranches := make(map[string]bool)
+	toRemove := make([]git.SessionInfo, len(candidates))
+	for i, c := range candidates {
+		toRemove[i] = c.report.Session
+		mergedBranches[c.report.Session.Branch] = c.report.Merged()
+	}
+	removed, err := removeSessions(manager, toRemove, func(s git.SessionInfo) bool { return mergedBranches[s.Branch] }, force)
+
+	fmt.Println()
+	if manager.IsDryRun() {
+		ui.Infof("Would remove %d out of %d sessions", removed, len(toRemove))
+	} else if removed == len(toRemove) {
+		ui.Successf("✅ Removed %d session(s)", removed)
+	} else {
+		ui.Infof("Removed %d out of %d sessions", removed, len(toRemove))
+	}
+	return err
+}
+
+// removeSessions removes each session, deleting its branch if deleteBranch
+// says so, and returns how many were removed. Unless force is set, sessions
+// whose removal would lose work are kept, listing that work. The error
+// says why not every session was removed.
+func removeSessions(manager *session.Manager, sessions []git.SessionInfo, deleteBranch func(git.SessionInfo) bool, force bool) (int, error) {
+	removed, kept, failed := 0, 0, 0
+	for _, s := range sessions {
+		opts := session.RemoveOptions{Branch: s.Branch, DeleteBranch: deleteBranch(s), Force: force}
+		if !force {
+			loss, err := manager.CheckRemoval(s.Path, s.Branch, opts.DeleteBranch)
+			if err != nil {
+				ui.Errorf("✗ Failed to check %s for unsaved work: %v", s.Name, err)
+				failed++
+				continue
+			}
+			if !loss.Empty() {
+				ui.Warningf("⚠️  Kept %s, removing it would lose %s:", s.Name, loss)
+				printLoss(loss)
+				kept++
+				continue
+			}
+		}
+
+		if err := manager.RemoveSession(s.Path, opts); err != nil {
+			ui.Errorf("✗ Failed to remove %s: %v", s.Name, err)
+			failed++
+		} else if manager.IsDryRun() {
+			ui.Successf("✓ Would remove: %s", s.Name)
+			removed++
+		} else {
+			ui.Successf("✓ Successfully removed: %s", s.Name)
+			removed++
+		}
+	}
+	if removed > 0 && !manager.IsDryRun() {
+		ui.Info("Removed sessions are in the trash; 'ccswitch trash list' shows them")
+	}
+
+	switch {
+	case failed > 0:
+		return removed, fmt.Errorf("%d session(s) could not be removed", failed)
+	case kept > 0:
+		return removed, fmt.Errorf("%w: kept %d session(s) to avoid losing work", errors.ErrUncommittedChanges, kept)
+	default:
+		return removed, nil
+	}
+}
+
+// printLoss lists the work removing a session would throw away
+func printLoss(loss *session.Loss) {
+	sections := []struct {
+		title string
+		lines []string
+	}{
+		{"Uncommitted changes:", loss.Changed},
+		{"Untracked files:", loss.Untracked},
+		{"Commits on no other branch:", loss.Commits},
+	}
+	for _, section := range sections {
+		if len(section.lines) == 0 {
+			continue
+		}
+		ui.Infof("  %s", section.title)
+		for _, line := range section.lines {
+			ui.Infof("    %s", line)
+		}
+	}
+}
+
+// confirmLoss asks the user to type the session's name to throw its work
+// away. Without a terminal to ask on, the answer is no.
+func confirmLoss(scanner *bufio.Scanner, name string) bool {
+	if !utils.IsInteractive() {
+		return false
+	}
+	fmt.Printf("Type %q to remove it anyway: ", name)
+	return scanner.Scan() && strings.TrimSpace(scanner.Text()) == name
+}
+
+func switchToDefaultBranch(cmd *cobra.Command, manager *session.Manager) {
+	branch := manager.DefaultBranch()
+	if _, err := gitClient(cmd).Run("", "checkout", branch); err != nil {
+		ui.Infof("ℹ Could not switch to %s branch", branch)
+		return
+	}
+	ui.Successf("✓ Switched to %s branch", branch)
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// addRunFlags registers the flags controlling how commands make changes
+func addRunFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolP("verbose", "v", false, "Print every git command and filesystem change with how long it took")
+	cmd.PersistentFlags().Bool("dry-run", false, "Print the git commands and filesystem changes that would be made, without making them")
+}
+
+func isVerbose(cmd *cobra.Command) bool {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	return verbose
+}
+
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	return dryRun
+}
+
+// gitClient returns the client a command runs git through, under the
+// command's context. With --verbose it echoes each git command to stderr;
+// with --dry-run it prints the commands that would change anything there
+// instead of running them.
+func gitClient(cmd *cobra.Command) git.Client {
+	runner := git.ExecRunner{}
+	if isVerbose(cmd) {
+		runner.Trace = os.Stderr
+	}
+	client := git.NewClient(cmd.Context(), runner)
+	if isDryRun(cmd) {
+		client = client.DryRun(os.Stderr)
+	}
+	return client
+}
+
+// newManager creates the session manager for the repository containing dir,
+// honouring --verbose and --dry-run
+func newManager(cmd *cobra.Command, dir string) *session.Manager {
+	return configureManager(cmd, session.NewManagerWithClient(gitClient(cmd), dir))
+}
+
+// configureManager makes a manager created elsewhere, such as by
+// session.ListAllSessions, honour --verbose and --dry-run
+func configureManager(cmd *cobra.Command, manager *session.Manager) *session.Manager {
+	if isVerbose(cmd) {
+		manager.SetTrace(os.Stderr)
+	}
+	if isDryRun(cmd) {
+		manager.SetDryRun(os.Stderr)
+	}
+	return manager
+}
+
+// printCd prints the line the shell wrapper looks for to change into path.
+// A dry run created nothing to change into.
+func printCd(manager *session.Manager, path string) {
+	if manager.IsDryRun() {
+		fmt.Fprintf(os.Stderr, "[dry-run] cd %s\n", path)
+		return
+	}
+	fmt.Printf("\ncd %s\n", path)
+}
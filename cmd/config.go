@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/ksred/ccswitch/internal/config"
 	"github.com/ksred/ccswitch/internal/ui"
@@ -10,31 +11,41 @@ import (
 
 func newConfigCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "config",
-		Short: "Show ccswitch configuration",
-		Run:   showConfig,
+		Use:         "config",
+		Short:       "Show ccswitch configuration",
+		Annotations: map[string]string{outputAnnotation: "json,yaml"},
+		RunE:        showConfig,
 	}
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "path",
 		Short: "Show config file path",
-		Run:   showConfigPath,
+		RunE:  showConfigPath,
 	})
 
 	cmd.AddCommand(&cobra.Command{
 		Use:   "init",
 		Short: "Create default config file",
-		Run:   initConfig,
+		RunE:  initConfig,
 	})
 
 	return cmd
 }
 
-func showConfig(cmd *cobra.Command, args []string) {
-	cfg, err := config.Load()
+func showConfig(cmd *cobra.Command, args []string) error {
+	// Include the current repository's .ccswitch.yaml when there is one
+	repoPath, _ := os.Getwd()
+	if mainRepoPath, err := gitClient(cmd).GetMainRepoPath(repoPath); err == nil {
+		repoPath = mainRepoPath
+	}
+
+	cfg, err := config.LoadForRepo(repoPath)
 	if err != nil {
-		ui.Errorf("✗ Failed to load config: %v", err)
-		return
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if machineOutput(cmd) {
+		return render(cmd, cfg)
 	}
 
 	ui.Title("⚙️  ccswitch Configuration")
@@ -45,7 +56,16 @@ func showConfig(cmd *cobra.Command, args []string) {
 	fmt.Println()
 
 	ui.Success("Worktree:")
+	ui.Infof("  Layout: %s", cfg.Worktree.Layout)
 	ui.Infof("  Relative path: %s", cfg.Worktree.RelativePath)
+	if len(cfg.Worktree.CarryOver.Patterns) == 0 {
+		ui.Info("  Carry over: (none)")
+	} else {
+		ui.Infof("  Carry over (%s):", cfg.Worktree.CarryOver.Mode)
+		for _, pattern := range cfg.Worktree.CarryOver.Patterns {
+			ui.Infof("    - %s", pattern)
+		}
+	}
 	fmt.Println()
 
 	ui.Success("UI:")
@@ -54,27 +74,72 @@ func showConfig(cmd *cobra.Command, args []string) {
 	fmt.Println()
 
 	ui.Success("Git:")
-	ui.Infof("  Default branch: %s", cfg.Git.DefaultBranch)
+	if cfg.Git.DefaultBranch == "" {
+		ui.Infof("  Default branch: (detect) %s", gitClient(cmd).DefaultBranch(repoPath, ""))
+	} else {
+		ui.Infof("  Default branch: %s", cfg.Git.DefaultBranch)
+	}
+	if len(cfg.Git.ProtectedBranches) == 0 {
+		ui.Info("  Protected branches: (default branch only)")
+	} else {
+		ui.Info("  Protected branches:")
+		for _, pattern := range cfg.Git.ProtectedBranches {
+			ui.Infof("    - %s", pattern)
+		}
+	}
 	ui.Infof("  Auto fetch: %v", cfg.Git.AutoFetch)
+	ui.Infof("  Fetch interval: %s", cfg.Git.FetchInterval)
+	fmt.Println()
+
+	ui.Success("Hooks:")
+	printAllHooks(cfg.Hooks)
 	fmt.Println()
 
+	if !cfg.UntrustedHooks.Empty() {
+		ui.Warningf("Untrusted hooks in %s, which run once you review the file and run 'ccswitch trust':", config.RepoConfigFile)
+		printAllHooks(cfg.UntrustedHooks)
+		fmt.Println()
+	}
+
 	configPath := config.GetConfigPath()
 	ui.Infof("Config file: %s", configPath)
+	return nil
+}
+
+func printAllHooks(h config.Hooks) {
+	printHooks("pre_create", h.PreCreate)
+	printHooks("post_create", h.PostCreate)
+	printHooks("pre_switch", h.PreSwitch)
+	printHooks("post_switch", h.PostSwitch)
+	printHooks("pre_remove", h.PreRemove)
+	printHooks("post_remove", h.PostRemove)
+}
+
+func printHooks(event string, commands []string) {
+	if len(commands) == 0 {
+		ui.Infof("  %s: (none)", event)
+		return
+	}
+	ui.Infof("  %s:", event)
+	for _, command := range commands {
+		ui.Infof("    - %s", command)
+	}
 }
 
-func showConfigPath(cmd *cobra.Command, args []string) {
+func showConfigPath(cmd *cobra.Command, args []string) error {
 	fmt.Println(config.GetConfigPath())
+	return nil
 }
 
-func initConfig(cmd *cobra.Command, args []string) {
+func initConfig(cmd *cobra.Command, args []string) error {
 	cfg := config.DefaultConfig()
 	if err := cfg.Save(); err != nil {
-		ui.Errorf("✗ Failed to create config: %v", err)
-		return
+		return fmt.Errorf("failed to create config: %w", err)
 	}
 
 	configPath := config.GetConfigPath()
 	ui.Successf("✓ Created default config at: %s", configPath)
 	fmt.Println()
 	fmt.Println("You can now edit this file to customize ccswitch behavior.")
+	return nil
 }
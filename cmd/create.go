@@ -7,8 +7,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/ksred/ccswitch/internal/config"
-	"github.com/ksred/ccswitch/internal/errors"
 	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
 	"github.com/ksred/ccswitch/internal/utils"
@@ -16,73 +14,94 @@ import (
 )
 
 func newCreateCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "create",
+	cmd := &cobra.Command{
+		Use:   "create [description]",
 		Short: "Create a new session",
-		Run:   createSession,
+		Long: `Create a new session.
+
+Without a description you are prompted for one. Passing the description as
+an argument skips the prompt, which makes create usable from scripts:
+
+  ccswitch create "fix auth bug"
+  ccswitch create "fix auth bug" --branch bugfix/auth --from develop
+  ccswitch create "fix auth bug" --no-cd`,
+		RunE: createSession,
 	}
+
+	addCreateFlags(cmd)
+
+	return cmd
+}
+
+// addCreateFlags registers the flags shared by create and the root command
+func addCreateFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from", "", "Branch, tag or commit to start the session from (default: the configured default branch)")
+	cmd.Flags().String("branch", "", "Branch name to use instead of one derived from the description")
+	cmd.Flags().Bool("no-cd", false, "Don't switch into the new session")
 }
 
-func createSession(cmd *cobra.Command, args []string) {
+func createSession(cmd *cobra.Command, args []string) error {
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Create session manager
-	manager := session.NewManager(currentDir)
+	manager := newManager(cmd, currentDir)
 
-	// Get description from user
-	fmt.Print(ui.TitleStyle.Render("🚀 What are you working on? "))
+	description := strings.TrimSpace(strings.Join(args, " "))
+	if description == "" {
+		// Never block on a prompt nobody can answer
+		if !utils.IsInteractive() {
+			return fmt.Errorf("no description given and stdin is not a terminal; pass it as an argument: ccswitch create \"<description>\"")
+		}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	if !scanner.Scan() {
-		return
+		// Get description from user
+		fmt.Print(ui.TitleStyle.Render("🚀 What are you working on? "))
+
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			return fmt.Errorf("no description given")
+		}
+		description = strings.TrimSpace(scanner.Text())
 	}
 
-	description := strings.TrimSpace(scanner.Text())
 	if description == "" {
-		ui.Error("✗ Description cannot be empty")
-		return
+		return fmt.Errorf("description cannot be empty")
 	}
 
-	// Create the session
-	if err := manager.CreateSession(description); err != nil {
-		ui.Errorf("✗ %s", err)
+	from, _ := cmd.Flags().GetString("from")
+	branch, _ := cmd.Flags().GetString("branch")
+	noCD, _ := cmd.Flags().GetBool("no-cd")
 
-		// Provide helpful tips based on error
-		hint := errors.ErrorHint(err)
-		if hint != "" {
-			ui.Infof("  Tip: %s", hint)
-		}
+	autoFetch(manager, "")
 
-		// Special handling for branch exists error
-		if errors.IsBranchExists(err) {
-			cfg, _ := config.Load()
-			branchName := cfg.Branch.Prefix + utils.Slugify(description)
-			ui.Infof("  Branch: %s", branchName)
-		}
-		return
+	opts := session.CreateOptions{
+		Description: description,
+		From:        from,
+		BranchName:  strings.TrimSpace(branch),
 	}
 
-	// Success!
-	sessionName := utils.Slugify(description)
-	cfg, _ := config.Load()
-	branchName := cfg.Branch.Prefix + sessionName
-	repoName := filepath.Base(currentDir)
-
-	// Get the full worktree path
-	homeDir, _ := os.UserHomeDir()
-	worktreePath := filepath.Join(homeDir, ".ccswitch", "worktrees", repoName, sessionName)
+	// Create the session
+	result, err := manager.CreateSession(opts)
+	if err != nil {
+		return err
+	}
 
-	ui.Successf("✓ Created session: %s", sessionName)
-	ui.Infof("Branch: %s", branchName)
-	ui.Infof("Location: ~/.ccswitch/worktrees/%s/%s", repoName, sessionName)
+	// Success!
+	created := result.Session
+	ui.Successf("✓ Created session: %s", created.Name)
+	ui.Infof("Branch: %s", created.Branch)
+	ui.Infof("Location: %s", displayPath(created.Path))
+	printCarriedOver(result.CarriedOver)
+
+	if noCD {
+		return nil
+	}
 
 	// Output the cd command for the shell wrapper to execute on a separate line
-	fmt.Printf("\ncd %s\n", worktreePath)
+	printCd(manager, created.Path)
 
 	// If shell integration is not active, show a helpful message
 	if !utils.IsShellIntegrationActive() {
@@ -90,4 +109,43 @@ func createSession(cmd *cobra.Command, args []string) {
 		ui.Info("💡 Note: Shell integration is not active.")
 		ui.Info(utils.GetShellIntegrationInstructions())
 	}
+
+	return nil
+}
+
+// autoFetch fetches the remote of branch, or of the default branch, when
+// git.auto_fetch asks for it. Being offline is no reason to stop; the
+// command carries on with what was fetched before.
+func autoFetch(manager *session.Manager, branch string) {
+	remote, err := manager.AutoFetch(branch)
+	switch {
+	case err == nil:
+	case remote != "":
+		ui.Warningf("⚠️  Couldn't fetch %s, using what was fetched before: %v", remote, err)
+	default:
+		ui.Warningf("⚠️  %v", err)
+	}
+}
+
+// printCarriedOver reports the untracked files brought into a new worktree
+func printCarriedOver(files []session.CarriedFile) {
+	if len(files) == 0 {
+		return
+	}
+	ui.Info("Carried over:")
+	for _, f := range files {
+		ui.Infof("  %s (%s)", f.Path, f.Mode)
+	}
+}
+
+// displayPath shortens paths under the home directory to ~/...
+func displayPath(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
+	}
+	return path
 }
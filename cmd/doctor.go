@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/doctor"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Find and repair inconsistent worktree state",
+		Long: `Check this repository's worktrees, the shared worktree storage and the shell
+integration for problems:
+
+  - directories in ~/.ccswitch/worktrees that git no longer knows about
+  - worktrees git still lists although their directory is gone
+  - worktrees whose .git file points at a moved repository
+  - sessions stored under the ID a repository had before it was moved
+  - worktrees moved without git
+  - branches checked out in more than one place
+  - a missing shell wrapper
+
+With --fix, repair what can be repaired with 'git worktree prune' and
+'git worktree repair', move stranded sessions under the repository's ID with
+their metadata and trash, and remove orphaned directories. Directories that still
+hold files are only removed after you confirm.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{outputAnnotation: "json,yaml"},
+		RunE:        runDoctor,
+	}
+
+	cmd.Flags().Bool("fix", false, "Repair the problems that can be repaired")
+
+	return cmd
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	problems, err := doctor.Check(gitClient(cmd), currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to check worktrees: %w", err)
+	}
+
+	if machineOutput(cmd) && !fix {
+		if err := render(cmd, append([]doctor.Problem{}, problems...)); err != nil {
+			return err
+		}
+		return problemsError(problems)
+	}
+
+	ui.Title("🩺 ccswitch doctor")
+	fmt.Println()
+
+	if len(problems) == 0 {
+		ui.Success("✓ No problems found")
+		return nil
+	}
+
+	for _, p := range problems {
+		if p.Severity == doctor.SeverityWarning {
+			ui.Warningf("⚠️  %s", p.Summary)
+		} else {
+			ui.Errorf("✗ %s", p.Summary)
+		}
+		for _, line := range strings.Split(p.Remedy, "\n") {
+			ui.Infof("   %s", line)
+		}
+	}
+	fmt.Println()
+
+	if !fix {
+		fixable := 0
+		for _, p := range problems {
+			if p.Fixable {
+				fixable++
+			}
+		}
+		if fixable > 0 {
+			ui.Infof("💡 Run 'ccswitch doctor --fix' to repair %d of them", fixable)
+		}
+		return problemsError(problems)
+	}
+
+	var remaining []doctor.Problem
+	for _, p := range problems {
+		if !p.Fixable {
+			remaining = append(remaining, p)
+			continue
+		}
+		if p.Confirm && !confirmRemoval(p.Path) {
+			ui.Infof("  Skipped %s", p.Path)
+			remaining = append(remaining, p)
+			continue
+		}
+		if p.Kind == doctor.OrphanDirectory && isDryRun(cmd) {
+			// Fix would remove it directly, not through git
+			fmt.Fprintf(os.Stderr, "[dry-run] rm -rf %s\n", p.Path)
+			continue
+		}
+		if err := doctor.Fix(gitClient(cmd), p); err != nil {
+			ui.Errorf("✗ Failed to fix %s: %v", p.Summary, err)
+			remaining = append(remaining, p)
+			continue
+		}
+		ui.Successf("✓ Fixed: %s", p.Summary)
+	}
+
+	if machineOutput(cmd) {
+		if err := render(cmd, append([]doctor.Problem{}, remaining...)); err != nil {
+			return err
+		}
+	}
+	return problemsError(remaining)
+}
+
+// confirmRemoval asks before removing a directory that still holds files;
+// without a terminal to ask on, the directory is kept
+func confirmRemoval(path string) bool {
+	if !utils.IsInteractive() {
+		return false
+	}
+	size := "unknown size"
+	if bytes, err := utils.DirSize(path); err == nil {
+		size = ui.FormatSize(bytes)
+	}
+	fmt.Fprintf(os.Stderr, "Remove %s and everything in it (%s)? (y/N): ", path, size)
+	scanner := bufio.NewScanner(os.Stdin)
+	return scanner.Scan() && strings.ToLower(scanner.Text()) == "y"
+}
+
+// problemsError fails the command when errors, as opposed to warnings, are left
+func problemsError(problems []doctor.Problem) error {
+	count := 0
+	for _, p := range problems {
+		if p.Severity == doctor.SeverityError {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%d problem(s) need attention", count)
+	}
+	return nil
+}
@@ -5,64 +5,112 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/version"
 	"github.com/spf13/cobra"
 )
 
+// infoReport is what ccswitch info shows; its json and yaml forms are part
+// of the --output schema
+type infoReport struct {
+	ConfigDir    string `json:"config_dir" yaml:"config_dir"`
+	WorktreesDir string `json:"worktrees_dir" yaml:"worktrees_dir"`
+	Layout       string `json:"layout" yaml:"layout"`
+
+	Repository struct {
+		Name string `json:"name" yaml:"name"`
+		// ID is empty outside a git repository
+		ID   string `json:"id" yaml:"id"`
+		Path string `json:"path" yaml:"path"`
+	} `json:"repository" yaml:"repository"`
+
+	Statistics struct {
+		Repositories int `json:"repositories" yaml:"repositories"`
+		Worktrees    int `json:"worktrees" yaml:"worktrees"`
+	} `json:"statistics" yaml:"statistics"`
+
+	Version version.Info `json:"version" yaml:"version"`
+}
+
 func newInfoCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "info",
-		Short: "Show ccswitch configuration and paths",
-		Run:   showInfo,
+		Use:         "info",
+		Short:       "Show ccswitch configuration and paths",
+		Annotations: map[string]string{outputAnnotation: "json,yaml"},
+		RunE:        showInfo,
 	}
 }
 
-func showInfo(cmd *cobra.Command, args []string) {
-	homeDir, _ := os.UserHomeDir()
-	ccSwitchDir := filepath.Join(homeDir, ".ccswitch")
-	worktreesDir := filepath.Join(ccSwitchDir, "worktrees")
+func showInfo(cmd *cobra.Command, args []string) error {
+	report := gatherInfo(cmd)
+	if machineOutput(cmd) {
+		return render(cmd, report)
+	}
 
 	ui.Title("📊 ccswitch Information")
 	fmt.Println()
 
 	// Paths
 	ui.Success("Paths:")
-	ui.Infof("  Config directory: %s", ccSwitchDir)
-	ui.Infof("  Worktrees stored in: %s", worktreesDir)
+	ui.Infof("  Config directory: %s", report.ConfigDir)
+	ui.Infof("  Worktrees stored in: %s", report.WorktreesDir)
+	if report.Layout != layout.Centralized {
+		ui.Infof("  Worktree layout: %s", report.Layout)
+	}
 	fmt.Println()
 
 	// Current repository
-	currentDir, _ := os.Getwd()
-	currentRepo := filepath.Base(currentDir)
 	ui.Success("Current Repository:")
-	ui.Infof("  Name: %s", currentRepo)
-	ui.Infof("  Path: %s", currentDir)
+	ui.Infof("  Name: %s", report.Repository.Name)
+	if report.Repository.ID != "" {
+		ui.Infof("  ID: %s", report.Repository.ID)
+	}
+	ui.Infof("  Path: %s", report.Repository.Path)
 	fmt.Println()
 
 	// Statistics
 	ui.Success("Statistics:")
-
-	// Count total worktrees
-	totalWorktrees := 0
-	repoCount := 0
-
-	if entries, err := os.ReadDir(worktreesDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() {
-				repoCount++
-				repoDir := filepath.Join(worktreesDir, entry.Name())
-				if sessions, err := os.ReadDir(repoDir); err == nil {
-					totalWorktrees += len(sessions)
-				}
-			}
-		}
-	}
-
-	ui.Infof("  Total repositories: %d", repoCount)
-	ui.Infof("  Total worktrees: %d", totalWorktrees)
+	ui.Infof("  Total repositories: %d", report.Statistics.Repositories)
+	ui.Infof("  Total worktrees: %d", report.Statistics.Worktrees)
 	fmt.Println()
 
 	// Version info
 	ui.Success("Version:")
-	ui.Infof("  ccswitch: %s", "1.0.0")
+	ui.Infof("  ccswitch: %s", report.Version.Version)
+	return nil
+}
+
+func gatherInfo(cmd *cobra.Command) infoReport {
+	var report infoReport
+
+	homeDir, _ := os.UserHomeDir()
+	report.ConfigDir = filepath.Join(homeDir, ".ccswitch")
+	report.WorktreesDir = filepath.Join(report.ConfigDir, "worktrees")
+	report.Layout = layout.Centralized
+	if cfg, err := config.Load(); err == nil {
+		report.Layout = cfg.Worktree.Layout
+	}
+
+	currentDir, _ := os.Getwd()
+	report.Repository.Name = filepath.Base(currentDir)
+	report.Repository.Path = currentDir
+	if repoID, err := gitClient(cmd).GetRepoID(currentDir); err == nil {
+		report.Repository.ID = repoID
+	}
+
+	// Count worktrees git still knows about; leftover directories are for 'ccswitch doctor'
+	if worktrees, err := session.StoredWorktrees(); err == nil {
+		repos := make(map[string]bool)
+		for _, wt := range worktrees {
+			repos[wt.MainRepo] = true
+		}
+		report.Statistics.Repositories = len(repos)
+		report.Statistics.Worktrees = len(worktrees)
+	}
+
+	report.Version = version.Get()
+	return report
 }
@@ -3,75 +3,209 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
 	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
-	"github.com/ksred/ccswitch/internal/utils"
 	"github.com/spf13/cobra"
 )
 
+// statusRefreshInterval is how often the selector re-reads session status
+const statusRefreshInterval = 5 * time.Second
+
 func newListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List and switch to sessions interactively",
-		Run:   listSessions,
+		Long: `List and switch to sessions interactively.
+
+With --all, list the sessions of every repository ccswitch knows about,
+grouped by repository. This works from any directory.
+
+With --output json, yaml or tsv, print every session (including the main
+repository) with its git status instead of opening the selector.
+
+With --names, print just the session names, one per line, without reading
+any worktree's status; shell completion uses this. With --all they are
+printed as <repo>/<session>.`,
+		Annotations: map[string]string{outputAnnotation: "json,yaml,tsv"},
+		RunE:        listSessions,
 	}
+
+	cmd.Flags().BoolP("all", "a", false, "List sessions of every repository")
+	cmd.Flags().Bool("names", false, "Only print session names, one per line")
+
+	return cmd
 }
 
-func listSessions(cmd *cobra.Command, args []string) {
+func listSessions(cmd *cobra.Command, args []string) error {
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	names, _ := cmd.Flags().GetBool("names")
+	if names && machineOutput(cmd) {
+		return errors.Usage(fmt.Errorf("--names can't be combined with --output"))
+	}
+
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		return listAllSessions(cmd, currentDir, names)
 	}
 
 	// Create session manager
-	manager := session.NewManager(currentDir)
+	manager := newManager(cmd, currentDir)
 
 	// Get sessions
 	sessions, err := manager.ListSessions()
 	if err != nil {
-		ui.Errorf("✗ Failed to list sessions: %v", err)
-		return
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	if names {
+		for _, s := range sessions {
+			fmt.Println(s.Name)
+		}
+		return nil
+	}
+
+	if machineOutput(cmd) {
+		manager.LoadStatuses(sessions)
+		// An empty list, not null, when there are no sessions
+		return render(cmd, append(sessionTable{}, sessions...))
+	}
+
+	if len(sessions) == 0 {
+		ui.Info("No active sessions")
+		return nil
+	}
+
+	if legacy := manager.LegacySessions(sessions); len(legacy) > 0 {
+		ui.Infof("💡 %d session(s) are stored under an old location; run 'ccswitch migrate' to move them", len(legacy))
+	}
+
+	// Use interactive selector; git status fills in as it loads
+	selector := ui.NewSessionSelector(sessions).WithStatus(manager.LoadStatuses, statusRefreshInterval)
+	selected, err := runSelector(selector)
+	if err != nil || selected == nil {
+		return err
+	}
+	return switchTo(manager, *selected)
+}
+
+// listAllSessions lists the sessions of every repository, grouped by repository
+func listAllSessions(cmd *cobra.Command, currentDir string, names bool) error {
+	repos, err := session.ListAllSessions(gitClient(cmd), currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
+	if names {
+		for i := range repos {
+			label := repos[i].Label(repos)
+			for _, s := range repos[i].Sessions {
+				fmt.Printf("%s/%s\n", label, s.Name)
+			}
+		}
+		return nil
+	}
+
+	if machineOutput(cmd) {
+		for i := range repos {
+			repos[i].Manager.LoadStatuses(repos[i].Sessions)
+		}
+		return render(cmd, append(repositoryTable{}, repos...))
+	}
+
+	var sessions []git.SessionInfo
+	var groups []string
+	for i := range repos {
+		label := fmt.Sprintf("%s (%s)", repos[i].Label(repos), repos[i].Path)
+		for _, s := range repos[i].Sessions {
+			sessions = append(sessions, s)
+			groups = append(groups, label)
+		}
+	}
 	if len(sessions) == 0 {
 		ui.Info("No active sessions")
-		return
+		return nil
 	}
 
-	// Use interactive selector
-	selector := ui.NewSessionSelector(sessions)
-	p := tea.NewProgram(selector)
+	// Each repository loads its own sessions' status against its own default branch
+	loadStatuses := func(sessions []git.SessionInfo) {
+		offset := 0
+		for _, repo := range repos {
+			repo.Manager.LoadStatuses(sessions[offset : offset+len(repo.Sessions)])
+			offset += len(repo.Sessions)
+		}
+	}
 
-	if _, err := p.Run(); err != nil {
-		ui.Errorf("✗ Failed to run selector: %v", err)
-		return
+	selector := ui.NewSessionSelector(sessions).WithGroups(groups).WithStatus(loadStatuses, statusRefreshInterval)
+	selected, err := runSelector(selector)
+	if err != nil || selected == nil {
+		return err
 	}
 
+	for _, repo := range repos {
+		for _, s := range repo.Sessions {
+			if s.Path == selected.Path {
+				return switchTo(configureManager(cmd, repo.Manager), *selected)
+			}
+		}
+	}
+	return nil
+}
+
+// runSelector shows the selector, returning nil if the user quit without choosing
+func runSelector(selector *ui.SessionSelector) (*git.SessionInfo, error) {
+	if _, err := tea.NewProgram(selector).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run selector: %w", err)
+	}
 	if selector.IsQuit() {
-		return
+		return nil, nil
 	}
+	return selector.GetSelected(), nil
+}
 
-	selected := selector.GetSelected()
-	if selected == nil {
-		return
+// sessionTable is the --output form of a list of sessions
+type sessionTable []git.SessionInfo
+
+func (t sessionTable) Header() []string {
+	return []string{"name", "branch", "path", "description", "base_branch", "changed", "untracked", "ahead", "behind", "compare"}
+}
+
+func (t sessionTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, s := range t {
+		row := []string{s.Name, s.Branch, s.Path, s.Description, s.BaseBranch, "", "", "", "", ""}
+		if st := s.Status; st != nil {
+			row[5], row[6] = strconv.Itoa(st.Changed), strconv.Itoa(st.Untracked)
+			row[7], row[8], row[9] = strconv.Itoa(st.Ahead), strconv.Itoa(st.Behind), st.Compare
+		}
+		rows = append(rows, row)
 	}
+	return rows
+}
 
-	// Output success message with consistent formatting
-	ui.Successf("✓ Switched to session: %s", selected.Name)
-	fmt.Printf("Branch: %s\n", selected.Branch)
-	fmt.Printf("Location: %s\n", selected.Path)
+// repositoryTable is the --output form of list --all
+type repositoryTable []session.Repository
 
-	// Output the cd command for shell evaluation
-	fmt.Printf("\ncd %s\n", selected.Path)
+func (t repositoryTable) Header() []string {
+	return append([]string{"repo"}, sessionTable{}.Header()...)
+}
 
-	// If shell integration is not active, show a helpful message
-	if !utils.IsShellIntegrationActive() {
-		fmt.Println()
-		ui.Info("💡 Note: Shell integration is not active.")
-		fmt.Println(utils.GetShellIntegrationInstructions())
+func (t repositoryTable) Rows() [][]string {
+	var rows [][]string
+	for i := range t {
+		label := t[i].Label(t)
+		for _, row := range sessionTable(t[i].Sessions).Rows() {
+			rows = append(rows, append([]string{label}, row...))
+		}
 	}
+	return rows
 }
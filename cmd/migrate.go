@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Move sessions to per-repository storage",
+		Long: `Move this repository's sessions into ~/.ccswitch/worktrees/<repo>-<id>/.
+
+Older versions stored every repository's sessions by directory name, so two
+clones called "api" shared a directory and could clash, and a repository
+moved before its ID was recorded gets a new one. Sessions now live under
+~/.ccswitch/worktrees/<repo>-<id>/, where the ID is unique per clone.
+Existing sessions keep working where they are; this command moves them with
+'git worktree move', keeping their branches and metadata.`,
+		Args: cobra.NoArgs,
+		RunE: migrateSessions,
+	}
+}
+
+func migrateSessions(cmd *cobra.Command, args []string) error {
+	// Get current directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Create session manager
+	manager := newManager(cmd, currentDir)
+
+	migrated, err := manager.MigrateSessions()
+	for _, m := range migrated {
+		ui.Successf("✓ Moved %s → %s", m.Session, displayPath(m.To))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(migrated) == 0 {
+		ui.Info("Nothing to migrate")
+		return nil
+	}
+
+	// If we were inside a moved session, follow it to its new location
+	for _, m := range migrated {
+		if rel, err := filepath.Rel(m.From, currentDir); err == nil && !strings.HasPrefix(rel, "..") {
+			printCd(manager, filepath.Join(m.To, rel))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/output"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// outputAnnotation lists the machine-readable formats a command supports,
+// e.g. "json,yaml,tsv". Commands without it only print text.
+const outputAnnotation = "ccswitch/output"
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("output", "o", string(output.Text), "Output format: text, json, yaml or tsv")
+}
+
+// checkOutput validates --output for the command about to run. In a machine
+// format stdout carries only the result, so messages move to stderr.
+func checkOutput(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return errors.Usage(err)
+	}
+	if !format.Machine() {
+		return nil
+	}
+
+	ui.SetOutput(os.Stderr)
+	for _, supported := range strings.Split(cmd.Annotations[outputAnnotation], ",") {
+		if supported == string(format) {
+			return nil
+		}
+	}
+	return errors.Usage(fmt.Errorf("'%s' does not support --output %s", cmd.CommandPath(), format))
+}
+
+func outputFormat(cmd *cobra.Command) (output.Format, error) {
+	value, _ := cmd.Flags().GetString("output")
+	return output.ParseFormat(value)
+}
+
+// machineOutput reports whether the command should print its result with render
+func machineOutput(cmd *cobra.Command) bool {
+	format, err := outputFormat(cmd)
+	return err == nil && format.Machine()
+}
+
+// render prints a command's result to stdout in the --output format
+func render(cmd *cobra.Command, v interface{}) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, format, v)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/ui"
+)
+
+func TestCheckOutput(t *testing.T) {
+	defer ui.SetOutput(os.Stdout)
+
+	root := NewRootCmd()
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"list"}, false},
+		{[]string{"list", "--output", "tsv"}, false},
+		{[]string{"status", "-o", "json"}, false},
+		{[]string{"info", "-o", "yaml"}, false},
+		{[]string{"info", "-o", "tsv"}, true},
+		{[]string{"switch", "-o", "json"}, true},
+		{[]string{"list", "-o", "xml"}, true},
+	}
+
+	for _, tt := range tests {
+		cmd, args, err := root.Find(tt.args)
+		if err != nil {
+			t.Fatalf("Find(%v) failed: %v", tt.args, err)
+		}
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags(%v) failed: %v", args, err)
+		}
+		err = checkOutput(cmd, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkOutput(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
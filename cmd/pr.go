@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
+	"github.com/ksred/ccswitch/internal/errors"
 	"github.com/ksred/ccswitch/internal/git"
-	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
 	"github.com/spf13/cobra"
 	"golang.org/x/text/cases"
@@ -18,51 +19,44 @@ func newPRCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "pr",
 		Short: "Create a pull request for the current session",
-		Run:   createPullRequest,
+		RunE:  createPullRequest,
 	}
 }
 
-func createPullRequest(cmd *cobra.Command, args []string) {
+func createPullRequest(cmd *cobra.Command, args []string) error {
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Check if gh CLI is available
 	if !isGitHubCLIAvailable() {
-		ui.Error("✗ GitHub CLI (gh) is not installed or not in PATH")
-		ui.Info("  Install GitHub CLI: https://cli.github.com/")
-		return
+		return fmt.Errorf("GitHub CLI (gh) is not installed or not in PATH; install it from https://cli.github.com/")
 	}
 
 	// Check if we're in a git repository
-	if !git.IsGitRepository(currentDir) {
-		ui.Error("✗ Not in a git repository")
-		return
+	client := gitClient(cmd)
+	if !client.IsGitRepository(currentDir) {
+		return errors.ErrNotGitRepository
 	}
 
 	// Get current branch
-	currentBranch, err := git.GetCurrentBranch(currentDir)
+	currentBranch, err := client.GetCurrentBranch(currentDir)
 	if err != nil {
-		ui.Errorf("✗ Failed to get current branch: %v", err)
-		return
+		return errors.Wrap(err, "failed to get current branch")
 	}
 
-	// Check if we're on main/master branch
-	if currentBranch == "main" || currentBranch == "master" {
-		ui.Error("✗ Cannot create PR from main/master branch")
-		ui.Info("  Switch to a feature branch first using 'ccswitch list'")
-		return
+	// Check we're not on the default branch or another protected one
+	manager := newManager(cmd, currentDir)
+	if manager.IsProtected(currentBranch) {
+		return fmt.Errorf("cannot create a PR from protected branch %s; switch to a feature branch first using 'ccswitch list'", currentBranch)
 	}
 
 	// Check if we're in a ccswitch session
-	manager := session.NewManager(currentDir)
 	sessions, err := manager.ListSessions()
 	if err != nil {
-		ui.Errorf("✗ Failed to list sessions: %v", err)
-		return
+		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
 	var currentSession *git.SessionInfo
@@ -75,40 +69,53 @@ func createPullRequest(cmd *cobra.Command, args []string) {
 	}
 
 	if currentSession == nil {
-		ui.Error("✗ Not in a ccswitch session directory")
-		ui.Info("  Use 'ccswitch list' to enter a session first")
-		return
+		return fmt.Errorf("%w: %s is not a ccswitch session directory", errors.ErrSessionNotFound, currentDir)
 	}
 
 	ui.Titlef("🚀 Creating pull request for session: %s", currentSession.Name)
 	ui.Infof("  Branch: %s", currentBranch)
 
-	// Check if branch has commits ahead of main
-	hasCommits, err := checkBranchHasCommits(currentDir, currentBranch)
+	// Check if branch has commits ahead of where the session started,
+	// falling back to the default branch for sessions ccswitch has no
+	// record of
+	base := currentSession.BaseCommit
+	if base == "" {
+		base = manager.LatestDefaultBranch()
+	}
+	if base == "" {
+		return fmt.Errorf("default branch not found; set git.default_branch")
+	}
+	hasCommits, err := checkBranchHasCommits(client, currentDir, base, currentBranch)
 	if err != nil {
-		ui.Errorf("✗ Failed to check branch commits: %v", err)
-		return
+		return errors.Wrap(err, "failed to check branch commits")
 	}
 
 	if !hasCommits {
-		ui.Error("✗ No commits found on this branch")
-		ui.Info("  Make some commits before creating a PR")
-		return
+		return fmt.Errorf("no commits found on %s; make some before creating a PR", currentBranch)
 	}
 
 	// Push the branch if needed
 	ui.Info("📤 Pushing branch to remote...")
-	if pushErr := pushBranch(currentDir, currentBranch); pushErr != nil {
-		ui.Errorf("✗ Failed to push branch: %v", pushErr)
-		return
+	if err := pushBranch(client, currentDir, currentBranch); err != nil {
+		return errors.Wrap(err, "failed to push branch")
 	}
 
 	// Create PR using gh CLI
 	ui.Info("📝 Creating pull request...")
-	prURL, err := createPRWithGH(currentDir, currentSession.Name)
+	if isDryRun(cmd) {
+		var quoted []string
+		for _, arg := range prCreateArgs(client, currentDir, *currentSession) {
+			if strings.ContainsAny(arg, " \t\"'") {
+				arg = strconv.Quote(arg)
+			}
+			quoted = append(quoted, arg)
+		}
+		fmt.Fprintf(os.Stderr, "[dry-run] gh %s\n", strings.Join(quoted, " "))
+		return nil
+	}
+	prURL, err := createPRWithGH(currentDir, prCreateArgs(client, currentDir, *currentSession))
 	if err != nil {
-		ui.Errorf("✗ Failed to create PR: %v", err)
-		return
+		return errors.Wrap(err, "failed to create PR")
 	}
 
 	ui.Successf("✓ Pull request created successfully!")
@@ -120,6 +127,7 @@ func createPullRequest(cmd *cobra.Command, args []string) {
 		ui.Errorf("✗ Failed to open browser: %v", err)
 		ui.Info("  You can manually open the URL above")
 	}
+	return nil
 }
 
 func isGitHubCLIAvailable() bool {
@@ -127,11 +135,8 @@ func isGitHubCLIAvailable() bool {
 	return err == nil
 }
 
-func checkBranchHasCommits(dir, branch string) (bool, error) {
-	cmd := exec.Command("git", "rev-list", "--count", "main.."+branch) // #nosec G204
-	cmd.Dir = dir
-
-	output, err := cmd.Output()
+func checkBranchHasCommits(client git.Client, dir, base, branch string) (bool, error) {
+	output, err := client.Run(dir, "rev-list", "--count", base+".."+branch)
 	if err != nil {
 		return false, err
 	}
@@ -140,21 +145,30 @@ func checkBranchHasCommits(dir, branch string) (bool, error) {
 	return count != "0", nil
 }
 
-func pushBranch(dir, branch string) error {
-	cmd := exec.Command("git", "push", "-u", "origin", branch)
-	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
+func pushBranch(client git.Client, dir, branch string) error {
+	_, err := client.RunCommand(git.Command{Dir: dir, Args: []string{"push", "-u", "origin", branch}, Interactive: true})
+	return err
 }
 
-func createPRWithGH(dir, sessionName string) (string, error) {
-	// Generate PR title from session name
-	title := strings.ReplaceAll(sessionName, "-", " ")
-	title = cases.Title(language.English).String(title)
+// prCreateArgs returns the gh arguments that open a pull request for session
+func prCreateArgs(client git.Client, dir string, session git.SessionInfo) []string {
+	// Prefer the description the session was created with, since slugifying
+	// loses punctuation and casing
+	title := session.Description
+	if title == "" {
+		title = strings.ReplaceAll(session.Name, "-", " ")
+		title = cases.Title(language.English).String(title)
+	}
+
+	args := []string{"pr", "create", "--title", title, "--body", "Created from ccswitch session: " + session.Name, "--web"}
+	if base := prBaseBranch(client, dir, session.BaseBranch); base != "" {
+		args = append(args, "--base", base)
+	}
+	return args
+}
 
-	cmd := exec.Command("gh", "pr", "create", "--title", title, "--body", "Created from ccswitch session: "+sessionName, "--web")
+func createPRWithGH(dir string, args []string) (string, error) {
+	cmd := exec.Command("gh", args...) // #nosec G204
 	cmd.Dir = dir
 
 	output, err := cmd.Output()
@@ -174,6 +188,20 @@ func createPRWithGH(dir, sessionName string) (string, error) {
 	return string(output), nil
 }
 
+// prBaseBranch maps the ref a session was created from to a branch name the
+// remote knows about. Tags and commits can't be PR bases, so they yield "".
+func prBaseBranch(client git.Client, dir, base string) string {
+	if base == "" {
+		return ""
+	}
+	branch := strings.TrimPrefix(base, "origin/")
+	bm := git.NewBranchManager(client, dir)
+	if bm.RefExists("refs/remotes/origin/"+branch) || bm.Exists(branch) {
+		return branch
+	}
+	return ""
+}
+
 func openInBrowser(url string) error {
 	var cmd *exec.Cmd
 
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func newRenameCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rename <session> <new description>",
+		Short: "Rename a session's branch and worktree",
+		Long: `Rename a session by giving it a new description.
+
+The worktree is moved so the session name matches again, and the branch
+ccswitch named after the session is renamed too, keeping its upstream
+tracking. A branch you named yourself, with 'create --branch' or by
+checking it out, keeps its name. If either step fails, both are rolled
+back.
+
+Examples:
+  ccswitch rename fix-auth "Fix OAuth token refresh"
+  ccswitch rename feature/fix-auth Fix OAuth token refresh`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: renameSession,
+	}
+}
+
+func renameSession(cmd *cobra.Command, args []string) error {
+	sessionName := args[0]
+	description := strings.TrimSpace(strings.Join(args[1:], " "))
+
+	// Get current directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Create session manager
+	manager := newManager(cmd, currentDir)
+
+	// Remember where the session lived before it moves
+	old, err := manager.FindSession(sessionName)
+	if err != nil {
+		return err
+	}
+
+	renamed, err := manager.RenameSession(sessionName, description)
+	if err != nil {
+		return err
+	}
+
+	ui.Successf("✓ Renamed session: %s → %s", old.Name, renamed.Name)
+	if renamed.Branch == old.Branch {
+		ui.Infof("Branch: %s (kept; you named it yourself)", renamed.Branch)
+	} else {
+		ui.Infof("Branch: %s → %s", old.Branch, renamed.Branch)
+	}
+	ui.Infof("Location: %s", renamed.Path)
+
+	// If we were inside the session, follow it to its new location
+	if rel, err := filepath.Rel(old.Path, currentDir); err == nil && !strings.HasPrefix(rel, "..") {
+		printCd(manager, filepath.Join(renamed.Path, rel))
+	}
+
+	return nil
+}
@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"io"
+	"os"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/output"
+	"github.com/ksred/ccswitch/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -14,30 +20,111 @@ context-switching overhead of stashing changes or switching branches.
 
 Key commands:
   ccswitch                    Create a new work session
+  ccswitch --from <ref>       Create a session branching from a specific ref
+  ccswitch create "<desc>"    Create a session without being prompted
   ccswitch checkout <branch>  Checkout an existing branch into a new worktree
   ccswitch list               Show and switch between sessions
+  ccswitch list --all         Sessions of every repository, from anywhere
+  ccswitch status             Show changes, PRs and disk usage of every session
   ccswitch switch <session>   Switch to a specific session
+  ccswitch switch <repo>/<s>  Switch to a session of another repository
+  ccswitch rename <s> <desc>  Rename a session's branch and worktree
+  ccswitch sync               Rebase every session onto the default branch
   ccswitch cleanup            Remove a session interactively
   ccswitch cleanup --all      Remove ALL worktrees at once (bulk cleanup)
+  ccswitch cleanup --merged   Remove sessions whose work has been merged
+  ccswitch undo               Restore the session removed last
+  ccswitch trash list         Show removed sessions that can be restored
+  ccswitch doctor [--fix]     Find and repair broken worktree state
   ccswitch pr                 Create a pull request for current session`,
-		Run: createSession,
+		RunE:              createSession,
+		PersistentPreRunE: checkOutput,
+		// Commands report their own failures through Execute
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
 
+	addCreateFlags(rootCmd)
+	addOutputFlag(rootCmd)
+	addRunFlags(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error { return errors.Usage(err) })
+
 	rootCmd.AddCommand(newCreateCmd())
 	rootCmd.AddCommand(newCheckoutCmd())
 	rootCmd.AddCommand(newListCmd())
+	rootCmd.AddCommand(newStatusCmd())
 	rootCmd.AddCommand(newSwitchCmd())
+	rootCmd.AddCommand(newRenameCmd())
+	rootCmd.AddCommand(newSyncCmd())
 	rootCmd.AddCommand(newCleanupCmd())
+	rootCmd.AddCommand(newUndoCmd())
+	rootCmd.AddCommand(newTrashCmd())
 	rootCmd.AddCommand(newInfoCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newDoctorCmd())
 	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newTrustCmd())
 	rootCmd.AddCommand(newPRCmd())
 	rootCmd.AddCommand(newShellInitCmd())
 	rootCmd.AddCommand(newVersionCmd())
+	markUsageErrors(rootCmd)
 
 	return rootCmd
 }
 
-// Execute runs the root command
+// Execute runs the root command and reports any error it returns on
+// stderr: as a message with a tip, or as an object with --output json or
+// yaml. main exits with errors.ExitCode of the error.
 func Execute() error {
-	return NewRootCmd().Execute()
+	cmd, err := NewRootCmd().ExecuteC()
+	if err != nil {
+		reportError(os.Stderr, cmd, err)
+	}
+	return err
+}
+
+// errorReport is how a failure is printed in a machine format. Kind and
+// ExitCode tell failures apart; see errors.ExitCode.
+type errorReport struct {
+	Error struct {
+		Message  string `json:"message" yaml:"message"`
+		Kind     string `json:"kind" yaml:"kind"`
+		ExitCode int    `json:"exit_code" yaml:"exit_code"`
+		Hint     string `json:"hint,omitempty" yaml:"hint,omitempty"`
+	} `json:"error" yaml:"error"`
+}
+
+// reportError prints a command's failure to w in the --output format
+func reportError(w io.Writer, cmd *cobra.Command, err error) {
+	if format, formatErr := outputFormat(cmd); formatErr == nil && (format == output.JSON || format == output.YAML) {
+		var report errorReport
+		report.Error.Message = err.Error()
+		report.Error.Kind = errors.Kind(err)
+		report.Error.ExitCode = errors.ExitCode(err)
+		report.Error.Hint = errors.ErrorHint(err)
+		if output.Render(w, format, report) == nil {
+			return
+		}
+	}
+
+	ui.SetOutput(w)
+	ui.Errorf("✗ %s", err)
+
+	// Provide helpful tips based on error
+	if hint := errors.ErrorHint(err); hint != "" {
+		ui.Infof("  Tip: %s", hint)
+	}
+}
+
+// markUsageErrors makes the argument checks of cmd and its subcommands fail
+// with errors.ErrUsage, so they exit with errors.ExitUsage
+func markUsageErrors(cmd *cobra.Command) {
+	if check := cmd.Args; check != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return errors.Usage(check(cmd, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/ui"
+)
+
+func TestUsageErrors(t *testing.T) {
+	defer ui.SetOutput(os.Stdout)
+
+	for _, args := range [][]string{
+		{"checkout"},
+		{"rename", "only-one"},
+		{"list", "--no-such-flag"},
+		{"switch", "anything", "-o", "json"},
+		{"list", "-o", "xml"},
+		{"list", "--names", "-o", "tsv"},
+	} {
+		root := NewRootCmd()
+		root.SetArgs(args)
+		root.SetOut(io.Discard)
+		_, err := root.ExecuteC()
+		if code := errors.ExitCode(err); code != errors.ExitUsage {
+			t.Errorf("%v: exit code %d (error %v), expected %d", args, code, err, errors.ExitUsage)
+		}
+	}
+}
+
+func TestReportError(t *testing.T) {
+	defer ui.SetOutput(os.Stdout)
+	err := errors.Wrap(errors.ErrSessionNotFound, "nope")
+
+	root := NewRootCmd()
+	cmd, args, findErr := root.Find([]string{"list", "-o", "json"})
+	if findErr != nil {
+		t.Fatalf("Find() failed: %v", findErr)
+	}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags() failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	reportError(&out, cmd, err)
+	var report errorReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("--output json printed %q, not a JSON object: %v", out.String(), err)
+	}
+	if report.Error.Message != "nope: session not found" || report.Error.Kind != "session_not_found" ||
+		report.Error.ExitCode != errors.ExitSessionMissing || report.Error.Hint == "" {
+		t.Errorf("report = %+v", report.Error)
+	}
+
+	out.Reset()
+	reportError(&out, NewRootCmd(), err)
+	if !strings.Contains(out.String(), "✗ nope: session not found") || !strings.Contains(out.String(), "Tip: ") {
+		t.Errorf("text report = %q, expected the error and a tip", out.String())
+	}
+}
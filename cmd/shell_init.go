@@ -22,11 +22,11 @@ For bash:
 For zsh:
   echo 'eval "$(ccswitch shell-init)"' >> ~/.zshrc
   source ~/.zshrc`,
-		Run: shellInit,
+		RunE: shellInit,
 	}
 }
 
-func shellInit(cmd *cobra.Command, args []string) {
+func shellInit(cmd *cobra.Command, args []string) error {
 	// Detect shell type
 	shell := os.Getenv("SHELL")
 	isZsh := os.Getenv("ZSH_VERSION") != "" || shell == "/bin/zsh" || shell == "/usr/bin/zsh"
@@ -36,6 +36,7 @@ func shellInit(cmd *cobra.Command, args []string) {
 	} else {
 		outputBashInit()
 	}
+	return nil
 }
 
 func outputBashInit() {
@@ -49,6 +50,7 @@ ccswitch() {
             
             # Run command with TTY preserved, redirect output to temp file
             CCSWITCH_SHELL_WRAPPER=1 command ccswitch "$@" | tee "$temp_file"
+            local ret=${PIPESTATUS[0]}
             
             # Extract and execute the cd command if session was selected
             local cd_cmd=$(grep "^cd " "$temp_file" 2>/dev/null | tail -1)
@@ -58,6 +60,7 @@ ccswitch() {
 
             # Clean up temp file
             rm -f "$temp_file"
+            return $ret
             ;;
         cleanup|info|shell-init)
             # These commands don't need special handling
@@ -70,6 +73,7 @@ ccswitch() {
 
             # Run command with stdin preserved, redirect output to temp file
             CCSWITCH_SHELL_WRAPPER=1 command ccswitch "$@" | tee "$temp_file"
+            local ret=${PIPESTATUS[0]}
 
             # Extract and execute the cd command if session was created successfully
             local cd_cmd=$(grep "^cd " "$temp_file" 2>/dev/null | tail -1)
@@ -79,6 +83,7 @@ ccswitch() {
             
             # Clean up temp file
             rm -f "$temp_file"
+            return $ret
             ;;
     esac
 }
@@ -90,8 +95,8 @@ _ccswitch_completions() {
     
     # Only provide completions for cleanup command
     if [[ "$prev" == "cleanup" ]]; then
-        # Get list of sessions by running list non-interactively
-        local sessions=$(CCSWITCH_SHELL_WRAPPER=1 command ccswitch list 2>&1 | grep -E "^→" | sed 's/^→ //' | awk '{print $1}')
+        # Only the names, so git status isn't read on every TAB
+        local sessions=$(command ccswitch list --names 2>/dev/null)
         COMPREPLY=($(compgen -W "$sessions" -- "$cur"))
     elif [[ "$COMP_CWORD" -eq 1 ]]; then
         # Complete command names
@@ -116,6 +121,7 @@ ccswitch() {
 
             # Run command with TTY preserved, redirect output to temp file
             CCSWITCH_SHELL_WRAPPER=1 command ccswitch "$@" | tee "$temp_file"
+            local ret=${pipestatus[1]}
 
             # Extract and execute the cd command if session was selected
             local cd_cmd=$(grep "^cd " "$temp_file" 2>/dev/null | tail -1)
@@ -125,6 +131,7 @@ ccswitch() {
 
             # Clean up temp file
             rm -f "$temp_file"
+            return $ret
             ;;
         cleanup|info|shell-init)
             # These commands don't need special handling
@@ -136,6 +143,7 @@ ccswitch() {
 
             # Run command with stdin preserved, redirect output to temp file
             CCSWITCH_SHELL_WRAPPER=1 command ccswitch "$@" | tee "$temp_file"
+            local ret=${pipestatus[1]}
 
             # Extract and execute the cd command if session was created successfully
             local cd_cmd=$(grep "^cd " "$temp_file" 2>/dev/null | tail -1)
@@ -145,6 +153,7 @@ ccswitch() {
 
             # Clean up temp file
             rm -f "$temp_file"
+            return $ret
             ;;
     esac
 }
@@ -163,7 +172,7 @@ _ccswitch() {
     case "$words[2]" in
         cleanup)
             # Get list of sessions for cleanup completion
-            sessions=(${(f)"$(CCSWITCH_SHELL_WRAPPER=1 command ccswitch list 2>&1 | grep -E '^→' | sed 's/^→ //' | awk '{print $1}')"})
+            sessions=(${(f)"$(command ccswitch list --names 2>/dev/null)"})
             _describe -t sessions 'session' sessions
             ;;
         *)
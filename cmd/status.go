@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of every session in this repository",
+		Long: `Show a table of every session in this repository: uncommitted changes,
+commits ahead/behind the default branch, upstream state, pull request (when
+the GitHub CLI is installed), disk usage, age and last activity.
+
+Examples:
+  ccswitch status                   # Every session
+  ccswitch status --dirty           # Only sessions with uncommitted changes
+  ccswitch status --stale 14d       # Only sessions untouched for two weeks
+  ccswitch status --merged          # Only sessions whose work has landed
+  ccswitch status --sort size       # Biggest worktrees first
+  ccswitch status --output json     # The same data for scripts`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{outputAnnotation: "json,yaml,tsv"},
+		RunE:        showStatus,
+	}
+
+	cmd.Flags().Bool("dirty", false, "Only show sessions with uncommitted changes")
+	cmd.Flags().String("stale", "", "Only show sessions without commits or switches for this long (e.g. 14d, 2w)")
+	cmd.Flags().Bool("merged", false, "Only show sessions whose work is on the default branch")
+	cmd.Flags().String("sort", "name", "Sort by name, age (oldest first), activity (least recent first) or size (largest first)")
+	cmd.Flags().Bool("no-pr", false, "Don't look up pull requests")
+
+	return cmd
+}
+
+func showStatus(cmd *cobra.Command, args []string) error {
+	dirtyOnly, _ := cmd.Flags().GetBool("dirty")
+	mergedOnly, _ := cmd.Flags().GetBool("merged")
+	noPR, _ := cmd.Flags().GetBool("no-pr")
+	sortBy, _ := cmd.Flags().GetString("sort")
+	staleFlag, _ := cmd.Flags().GetString("stale")
+
+	var staleAfter time.Duration
+	if staleFlag != "" {
+		var err error
+		if staleAfter, err = utils.ParseAge(staleFlag); err != nil {
+			return err
+		}
+	}
+	less, ok := reportOrders[sortBy]
+	if !ok {
+		return fmt.Errorf("invalid --sort %q, expected name, age, activity or size", sortBy)
+	}
+
+	// Get current directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Create session manager
+	manager := newManager(cmd, currentDir)
+	autoFetch(manager, "")
+
+	sessions, err := manager.ListSessions()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var worktrees []git.SessionInfo
+	for _, s := range sessions {
+		if !manager.IsMainRepo(s) {
+			worktrees = append(worktrees, s)
+		}
+	}
+	machine := machineOutput(cmd)
+	if len(worktrees) == 0 && !machine {
+		ui.Info("No active sessions")
+		return nil
+	}
+
+	reports := manager.Reports(worktrees, session.ReportOptions{PullRequests: !noPR, DiskUsage: true})
+
+	var shown []session.Report
+	for _, r := range reports {
+		if dirtyOnly && (r.Session.Status == nil || !r.Session.Status.Dirty()) {
+			continue
+		}
+		if mergedOnly && !r.Merged() {
+			continue
+		}
+		if staleAfter > 0 && !r.Stale(staleAfter) {
+			continue
+		}
+		shown = append(shown, r)
+	}
+	sort.SliceStable(shown, func(i, j int) bool { return less(&shown[i], &shown[j]) })
+
+	if machine {
+		return render(cmd, newStatusTable(shown))
+	}
+	if len(shown) == 0 {
+		ui.Info("No sessions match")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tBRANCH\tCHANGES\tDEFAULT\tUPSTREAM\tPR\tSIZE\tAGE\tACTIVE")
+	for i := range shown {
+		r := &shown[i]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Session.Name,
+			r.Session.Branch,
+			changesColumn(r),
+			defaultColumn(r),
+			upstreamColumn(r),
+			prColumn(r),
+			sizeColumn(r),
+			orDash(ui.FormatAge(r.Session.CreatedAt)),
+			orDash(ui.FormatAge(r.LastActivity())),
+		)
+	}
+	return w.Flush()
+}
+
+// reportOrders are the orderings available to --sort
+var reportOrders = map[string]func(a, b *session.Report) bool{
+	"name": func(a, b *session.Report) bool { return a.Session.Name < b.Session.Name },
+	"age": func(a, b *session.Report) bool {
+		return olderFirst(a.Session.CreatedAt, b.Session.CreatedAt)
+	},
+	"activity": func(a, b *session.Report) bool {
+		return olderFirst(a.LastActivity(), b.LastActivity())
+	},
+	"size": func(a, b *session.Report) bool { return a.DiskUsage > b.DiskUsage },
+}
+
+// olderFirst orders times oldest first, with unknown times last
+func olderFirst(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return !a.IsZero()
+	}
+	return a.Before(b)
+}
+
+func changesColumn(r *session.Report) string {
+	if r.Session.Status == nil {
+		return "?"
+	}
+	return ui.FormatChanges(r.Session.Status)
+}
+
+func defaultColumn(r *session.Report) string {
+	if r.DefaultBranch == "" {
+		return "-"
+	}
+	if r.Ahead == 0 && r.Behind == 0 {
+		return "in sync"
+	}
+	return ui.FormatAheadBehind(r.Ahead, r.Behind)
+}
+
+func upstreamColumn(r *session.Report) string {
+	s := r.Session.Status
+	switch {
+	case s == nil || s.Upstream == "":
+		return "-"
+	case s.UpstreamGone:
+		return "gone"
+	case s.Compare != s.Upstream:
+		return "-"
+	case s.Ahead == 0 && s.Behind == 0:
+		return "in sync"
+	default:
+		return ui.FormatAheadBehind(s.Ahead, s.Behind)
+	}
+}
+
+func prColumn(r *session.Report) string {
+	if r.PR == nil {
+		return "-"
+	}
+	return fmt.Sprintf("#%d %s", r.PR.Number, strings.ToLower(r.PR.State))
+}
+
+func sizeColumn(r *session.Report) string {
+	if r.DiskUsage < 0 {
+		return "?"
+	}
+	return ui.FormatSize(r.DiskUsage)
+}
+
+// statusEntry is the --output form of a session's report
+type statusEntry struct {
+	Session git.SessionInfo `json:"session" yaml:"session"`
+
+	// Commits ahead of and behind DefaultBranch
+	DefaultBranch string `json:"default_branch" yaml:"default_branch"`
+	AheadDefault  int    `json:"ahead_default" yaml:"ahead_default"`
+	BehindDefault int    `json:"behind_default" yaml:"behind_default"`
+
+	Merged       bool         `json:"merged" yaml:"merged"`
+	LastActivity time.Time    `json:"last_activity" yaml:"last_activity"`
+	DiskUsage    int64        `json:"disk_usage" yaml:"disk_usage"`
+	PullRequest  *pullRequest `json:"pull_request,omitempty" yaml:"pull_request,omitempty"`
+}
+
+type pullRequest struct {
+	Number int    `json:"number" yaml:"number"`
+	State  string `json:"state" yaml:"state"`
+	URL    string `json:"url" yaml:"url"`
+}
+
+// statusTable is the --output form of the status dashboard
+type statusTable []statusEntry
+
+func newStatusTable(reports []session.Report) statusTable {
+	table := statusTable{}
+	for i := range reports {
+		r := &reports[i]
+		entry := statusEntry{
+			Session:       r.Session,
+			DefaultBranch: r.DefaultBranch,
+			AheadDefault:  r.Ahead,
+			BehindDefault: r.Behind,
+			Merged:        r.Merged(),
+			LastActivity:  r.LastActivity(),
+			DiskUsage:     r.DiskUsage,
+		}
+		if r.PR != nil {
+			entry.PullRequest = &pullRequest{Number: r.PR.Number, State: strings.ToLower(r.PR.State), URL: r.PR.URL}
+		}
+		table = append(table, entry)
+	}
+	return table
+}
+
+func (t statusTable) Header() []string {
+	return []string{"name", "branch", "path", "changed", "untracked", "default_branch", "ahead_default", "behind_default",
+		"upstream", "upstream_gone", "merged", "pr_number", "pr_state", "disk_usage", "created_at", "last_activity"}
+}
+
+func (t statusTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, e := range t {
+		s := e.Session
+		changed, untracked, upstream, gone := "", "", "", ""
+		if st := s.Status; st != nil {
+			changed, untracked = strconv.Itoa(st.Changed), strconv.Itoa(st.Untracked)
+			upstream, gone = st.Upstream, strconv.FormatBool(st.UpstreamGone)
+		}
+		prNumber, prState := "", ""
+		if e.PullRequest != nil {
+			prNumber, prState = strconv.Itoa(e.PullRequest.Number), e.PullRequest.State
+		}
+		rows = append(rows, []string{
+			s.Name, s.Branch, s.Path, changed, untracked,
+			e.DefaultBranch, strconv.Itoa(e.AheadDefault), strconv.Itoa(e.BehindDefault),
+			upstream, gone, strconv.FormatBool(e.Merged), prNumber, prState,
+			strconv.FormatInt(e.DiskUsage, 10), formatTime(s.CreatedAt), formatTime(e.LastActivity),
+		})
+	}
+	return rows
+}
+
+// formatTime renders a TSV timestamp, empty when unknown
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/ksred/ccswitch/internal/errors"
 	"github.com/ksred/ccswitch/internal/git"
 	"github.com/ksred/ccswitch/internal/session"
 	"github.com/ksred/ccswitch/internal/ui"
@@ -15,65 +17,87 @@ func newSwitchCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "switch <session>",
 		Short: "Switch to a specific session",
-		Long: `Switch to a specific session by name.
+		Long: `Switch to a specific session by name or branch.
 
-The session name can be a partial match or the full name.
-If multiple sessions match, the first one is selected.`,
+Use <repo>/<session> to switch to a session of another repository; this
+works from any directory. <repo> is the repository's directory name, or its
+ID (see 'ccswitch list --all') when two repositories share a name.`,
 		Args: cobra.ExactArgs(1),
-		Run:  switchSession,
+		RunE: switchSession,
 	}
 }
 
-func switchSession(cmd *cobra.Command, args []string) {
+func switchSession(cmd *cobra.Command, args []string) error {
 	sessionName := args[0]
 
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		ui.Error("✗ Failed to get current directory")
-		return
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	// Create session manager
-	manager := session.NewManager(currentDir)
+	// A session of the current repository wins, since branch names also contain slashes
+	var sessions []git.SessionInfo
+	var manager *session.Manager
+	if gitClient(cmd).IsGitRepository(currentDir) {
+		manager = newManager(cmd, currentDir)
+		sessions, err = manager.ListSessions()
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		for _, s := range sessions {
+			if s.Name == sessionName || s.Branch == sessionName {
+				return switchTo(manager, s)
+			}
+		}
+	}
 
-	// Get sessions
-	sessions, err := manager.ListSessions()
-	if err != nil {
-		ui.Errorf("✗ Failed to list sessions: %v", err)
-		return
+	if strings.Contains(sessionName, "/") {
+		repos, err := session.ListAllSessions(gitClient(cmd), currentDir)
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		repo, selected, err := session.FindInRepositories(repos, sessionName)
+		if err == nil {
+			return switchTo(configureManager(cmd, repo.Manager), *selected)
+		}
+		if !errors.IsSessionNotFound(err) || manager == nil {
+			return err
+		}
 	}
 
+	if manager == nil {
+		return fmt.Errorf("%w; use <repo>/<session> to switch to another repository's session", errors.ErrNotGitRepository)
+	}
 	if len(sessions) == 0 {
-		ui.Info("No active sessions")
-		return
+		return errors.Wrap(errors.ErrNoSessions, "cannot switch to "+sessionName)
 	}
 
-	// Find the session
-	var selected *git.SessionInfo
+	ui.Info("Available sessions:")
 	for _, s := range sessions {
-		if s.Name == sessionName || s.Branch == sessionName {
-			selected = &s
-			break
-		}
+		fmt.Printf("  %s (%s)\n", s.Name, s.Branch)
 	}
+	return errors.Wrap(errors.ErrSessionNotFound, sessionName)
+}
 
-	if selected == nil {
-		ui.Errorf("✗ Session '%s' not found", sessionName)
-		ui.Info("Available sessions:")
-		for _, s := range sessions {
-			fmt.Printf("  %s (%s)\n", s.Name, s.Branch)
-		}
-		return
+// switchTo runs the session's switch hooks and prints the cd line the shell
+// wrapper looks for
+func switchTo(manager *session.Manager, selected git.SessionInfo) error {
+	// Run switch hooks; a failing pre_switch hook cancels the switch
+	if err := manager.SwitchSession(selected); err != nil {
+		return err
 	}
 
 	// Output success message with consistent formatting
 	ui.Successf("✓ Switched to session: %s", selected.Name)
+	if selected.Description != "" {
+		fmt.Printf("Description: %s\n", selected.Description)
+	}
 	fmt.Printf("Branch: %s\n", selected.Branch)
 	fmt.Printf("Location: %s\n", selected.Path)
 
 	// Output the cd command for shell evaluation
-	fmt.Printf("\ncd %s\n", selected.Path)
+	printCd(manager, selected.Path)
 
 	// If shell integration is not active, show a helpful message
 	if !utils.IsShellIntegrationActive() {
@@ -81,4 +105,5 @@ func switchSession(cmd *cobra.Command, args []string) {
 		ui.Info("💡 Note: Shell integration is not active.")
 		fmt.Println(utils.GetShellIntegrationInstructions())
 	}
+	return nil
 }
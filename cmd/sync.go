@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [session...]",
+		Short: "Bring sessions up to date with the default branch",
+		Long: `Rebase every session's branch onto the default branch (git.default_branch),
+or merge the default branch into it with --merge. Name sessions to sync only
+those. When the local default branch is merely behind origin, sessions are
+brought up to date with origin's.
+
+Sessions with uncommitted changes are skipped. A conflict stops that
+session's rebase or merge, which is aborted so the session is left as it
+was, unless --leave-conflicts is given. Other sessions are synced either
+way.
+
+With git.auto_fetch set, the default branch's remote is fetched first
+unless it was within git.fetch_interval. --fetch fetches every remote
+regardless; --fetch=false never fetches.
+
+Examples:
+  ccswitch sync                     # Rebase every session
+  ccswitch sync --fetch             # Fetch every remote first
+  ccswitch sync fix-auth --merge    # Merge instead of rebasing one session`,
+		Annotations: map[string]string{outputAnnotation: "json,yaml"},
+		RunE:        syncSessions,
+	}
+
+	cmd.Flags().Bool("merge", false, "Merge the default branch in instead of rebasing")
+	cmd.Flags().Bool("fetch", false, "Fetch every remote first, whatever git.auto_fetch says")
+	cmd.Flags().Bool("leave-conflicts", false, "Leave conflicted rebases and merges for you to resolve instead of aborting them")
+
+	return cmd
+}
+
+func syncSessions(cmd *cobra.Command, args []string) error {
+	merge, _ := cmd.Flags().GetBool("merge")
+	leaveConflicts, _ := cmd.Flags().GetBool("leave-conflicts")
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	manager := newManager(cmd, currentDir)
+
+	if !cmd.Flags().Changed("fetch") {
+		autoFetch(manager, "")
+	} else if fetch, _ := cmd.Flags().GetBool("fetch"); fetch {
+		ui.Info("Fetching...")
+		if err := manager.Fetch(); err != nil {
+			ui.Warningf("⚠️  %v", err)
+			ui.Info("Syncing with what was fetched before")
+		}
+	}
+
+	sessions, err := manager.ListSessions()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	targets, err := syncTargets(manager, sessions, args)
+	if err != nil {
+		return err
+	}
+
+	base := manager.LatestDefaultBranch()
+	if base == "" {
+		return fmt.Errorf("default branch not found; set git.default_branch")
+	}
+
+	opts := session.SyncOptions{Merge: merge, LeaveConflicts: leaveConflicts}
+	var results []session.SyncResult
+	for _, s := range targets {
+		r := manager.SyncSession(s, base, opts)
+		results = append(results, r)
+		if !machineOutput(cmd) {
+			printSyncResult(r, base, merge, manager.IsDryRun())
+		}
+	}
+
+	if machineOutput(cmd) {
+		if err := render(cmd, append([]session.SyncResult{}, results...)); err != nil {
+			return err
+		}
+	} else {
+		printSyncSummary(results, manager.IsDryRun())
+	}
+	return syncError(results)
+}
+
+// syncTargets picks the sessions named in args, or every session besides
+// the main repository
+func syncTargets(manager *session.Manager, sessions []git.SessionInfo, args []string) ([]git.SessionInfo, error) {
+	if len(args) == 0 {
+		var targets []git.SessionInfo
+		for _, s := range sessions {
+			if !manager.IsMainRepo(s) {
+				targets = append(targets, s)
+			}
+		}
+		return targets, nil
+	}
+
+	var targets []git.SessionInfo
+	for _, name := range args {
+		s, err := manager.FindSession(name)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, *s)
+	}
+	return targets, nil
+}
+
+// printSyncResult reports how one session was synced. A dry run only
+// says what would have happened to it.
+func printSyncResult(r session.SyncResult, base string, merge, dryRun bool) {
+	name := r.Session.Name
+	switch r.Outcome {
+	case session.SyncUpdated:
+		verb := "Rebased onto"
+		switch {
+		case dryRun && merge:
+			verb = "Would merge"
+		case dryRun:
+			verb = "Would rebase onto"
+		case merge:
+			verb = "Merged"
+		}
+		ui.Successf("✓ %s: %s %s (%d new commit(s))", name, verb, base, r.Behind)
+	case session.SyncUpToDate:
+		ui.Infof("  %s: up to date", name)
+	case session.SyncSkipped:
+		ui.Warningf("⚠️  %s: skipped, %s", name, r.Detail)
+	case session.SyncConflict:
+		ui.Errorf("✗ %s: conflict, %s", name, r.Detail)
+		for _, file := range r.Conflicts {
+			ui.Infof("    %s", file)
+		}
+	default:
+		ui.Errorf("✗ %s: %s", name, r.Detail)
+	}
+}
+
+func printSyncSummary(results []session.SyncResult, dryRun bool) {
+	if len(results) == 0 {
+		ui.Info("No sessions to sync")
+		return
+	}
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Outcome]++
+	}
+	updated := "updated"
+	if dryRun {
+		updated = "would be updated"
+	}
+	var parts []string
+	for _, o := range []struct{ outcome, label string }{
+		{session.SyncUpdated, updated},
+		{session.SyncUpToDate, "up to date"},
+		{session.SyncSkipped, "skipped"},
+		{session.SyncConflict, "conflicted"},
+		{session.SyncFailed, "failed"},
+	} {
+		if counts[o.outcome] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[o.outcome], o.label))
+		}
+	}
+	verb := "Synced"
+	if dryRun {
+		verb = "Checked"
+	}
+	fmt.Println()
+	ui.Infof("%s %d session(s): %s", verb, len(results), strings.Join(parts, ", "))
+}
+
+// syncError fails the command when a session could not be brought up to
+// date, so scripts notice
+func syncError(results []session.SyncResult) error {
+	count := 0
+	for _, r := range results {
+		if r.Outcome == session.SyncConflict || r.Outcome == session.SyncFailed {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%d session(s) could not be synced", count)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newUndoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "undo",
+		Short: "Restore the most recently removed session",
+		Long: `Restore the session removed last, from the trash, exactly as it was:
+its branch, its worktree at the same path, and its staged, unstaged and
+untracked changes. Ignored files are not kept. Run it again to restore the
+session removed before that.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, err := trashManager(cmd)
+			if err != nil {
+				return err
+			}
+			entries, err := manager.Trash()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("%w: the trash is empty", errors.ErrNoSessions)
+			}
+			return restoreSession(manager, entries[0].ID)
+		},
+	}
+}
+
+func newTrashCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List, restore and purge removed sessions",
+		Long: `Every session cleanup removes goes to the trash first: its commit is kept
+under refs/ccswitch/trash/ and its uncommitted changes and untracked files
+under ~/.ccswitch/trash, until the trash is purged.
+
+Examples:
+  ccswitch trash list                     # Removed sessions, newest first
+  ccswitch trash restore my-feature       # Rebuild a removed session
+  ccswitch trash purge --older-than 30d   # Forget sessions removed a month ago`,
+	}
+
+	list := &cobra.Command{
+		Use:         "list",
+		Short:       "List removed sessions, most recently removed first",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{outputAnnotation: "json,yaml,tsv"},
+		RunE:        listTrash,
+	}
+
+	restore := &cobra.Command{
+		Use:   "restore <session>",
+		Short: "Rebuild a removed session, given its name or trash ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, err := trashManager(cmd)
+			if err != nil {
+				return err
+			}
+			return restoreSession(manager, args[0])
+		},
+	}
+
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete removed sessions for good",
+		Args:  cobra.NoArgs,
+		RunE:  purgeTrash,
+	}
+	purge.Flags().String("older-than", "", "Only purge sessions removed at least this long ago (e.g. 30d, 2w)")
+	purge.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+
+	cmd.AddCommand(list, restore, purge)
+	return cmd
+}
+
+func trashManager(cmd *cobra.Command) (*session.Manager, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return newManager(cmd, currentDir), nil
+}
+
+func restoreSession(manager *session.Manager, ref string) error {
+	result, err := manager.RestoreFromTrash(ref)
+	if err != nil {
+		return err
+	}
+
+	restored := result.Session
+	ui.Successf("✓ Restored session: %s", restored.Name)
+	ui.Infof("Branch: %s", restored.Branch)
+	ui.Infof("Location: %s", displayPath(restored.Path))
+
+	// Output the cd command for the shell wrapper to execute on a separate line
+	printCd(manager, restored.Path)
+	return nil
+}
+
+func listTrash(cmd *cobra.Command, args []string) error {
+	manager, err := trashManager(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := manager.Trash()
+	if err != nil {
+		return err
+	}
+
+	if machineOutput(cmd) {
+		return render(cmd, newTrashTable(entries))
+	}
+	if len(entries) == 0 {
+		ui.Info("The trash is empty")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSESSION\tBRANCH\tSAVED\tREMOVED")
+	for _, e := range entries {
+		branch := orDash(e.Session.Branch)
+		if e.BranchDeleted {
+			branch += " (deleted)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Session.Name, branch, savedColumn(e), ui.FormatAge(e.RemovedAt))
+	}
+	return w.Flush()
+}
+
+func savedColumn(e session.TrashEntry) string {
+	var parts []string
+	if e.Changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", e.Changed))
+	}
+	if e.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", e.Untracked))
+	}
+	if len(parts) == 0 {
+		return "clean"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func purgeTrash(cmd *cobra.Command, args []string) error {
+	olderThanFlag, _ := cmd.Flags().GetString("older-than")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	var olderThan time.Duration
+	if olderThanFlag != "" {
+		var err error
+		if olderThan, err = utils.ParseAge(olderThanFlag); err != nil {
+			return err
+		}
+	}
+
+	manager, err := trashManager(cmd)
+	if err != nil {
+		return err
+	}
+
+	if olderThanFlag == "" && !yes {
+		entries, err := manager.Trash()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			ui.Info("The trash is empty")
+			return nil
+		}
+		if !utils.IsInteractive() {
+			return fmt.Errorf("refusing to empty the trash without confirmation; pass --yes or --older-than")
+		}
+		fmt.Printf("Delete all %d removed session(s) for good? (y/N): ", len(entries))
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() || strings.ToLower(strings.TrimSpace(scanner.Text())) != "y" {
+			ui.Info("Nothing purged")
+			return nil
+		}
+	}
+
+	purged, err := manager.PurgeTrash(olderThan)
+	for _, e := range purged {
+		ui.Successf("✓ Purged %s", e.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if len(purged) == 0 {
+		ui.Info("Nothing to purge")
+	}
+	return nil
+}
+
+// trashEntry is the --output form of a removed session
+type trashEntry struct {
+	ID            string    `json:"id" yaml:"id"`
+	Name          string    `json:"name" yaml:"name"`
+	Branch        string    `json:"branch" yaml:"branch"`
+	Path          string    `json:"path" yaml:"path"`
+	Head          string    `json:"head" yaml:"head"`
+	BranchDeleted bool      `json:"branch_deleted" yaml:"branch_deleted"`
+	Changed       int       `json:"changed" yaml:"changed"`
+	Untracked     int       `json:"untracked" yaml:"untracked"`
+	RemovedAt     time.Time `json:"removed_at" yaml:"removed_at"`
+}
+
+// trashTable is the --output form of the trash
+type trashTable []trashEntry
+
+func newTrashTable(entries []session.TrashEntry) trashTable {
+	table := trashTable{}
+	for _, e := range entries {
+		table = append(table, trashEntry{
+			ID:            e.ID,
+			Name:          e.Session.Name,
+			Branch:        e.Session.Branch,
+			Path:          e.Session.Path,
+			Head:          e.Head,
+			BranchDeleted: e.BranchDeleted,
+			Changed:       e.Changed,
+			Untracked:     e.Untracked,
+			RemovedAt:     e.RemovedAt,
+		})
+	}
+	return table
+}
+
+func (t trashTable) Header() []string {
+	return []string{"id", "name", "branch", "path", "head", "branch_deleted", "changed", "untracked", "removed_at"}
+}
+
+func (t trashTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, e := range t {
+		rows = append(rows, []string{e.ID, e.Name, e.Branch, e.Path, e.Head, strconv.FormatBool(e.BranchDeleted),
+			strconv.Itoa(e.Changed), strconv.Itoa(e.Untracked), formatTime(e.RemovedAt)})
+	}
+	return rows
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func newTrustCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Let the hooks in this repository's .ccswitch.yaml run",
+		Long: `Hooks in a repository's .ccswitch.yaml run shell commands on your machine,
+so they only run once you have read the file and trusted it. The trust is
+recorded in ~/.ccswitch/trust.json together with a hash of the file; when
+the file changes, its hooks stop running until you trust it again.
+
+Examples:
+  ccswitch trust            # Trust the hooks in this repository's .ccswitch.yaml
+  ccswitch trust --revoke   # Stop running them`,
+		Args: cobra.NoArgs,
+		RunE: trustRepo,
+	}
+
+	cmd.Flags().Bool("revoke", false, "Stop running the repository's hooks")
+
+	return cmd
+}
+
+func trustRepo(cmd *cobra.Command, args []string) error {
+	revoke, _ := cmd.Flags().GetBool("revoke")
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	repoPath, err := gitClient(cmd).GetMainRepoPath(currentDir)
+	if err != nil {
+		return errors.ErrNotGitRepository
+	}
+	configPath := filepath.Join(repoPath, config.RepoConfigFile)
+
+	if revoke {
+		if isDryRun(cmd) {
+			fmt.Fprintf(os.Stderr, "[dry-run] untrust %s\n", configPath)
+			return nil
+		}
+		if err := config.Untrust(repoPath); err != nil {
+			return fmt.Errorf("failed to update %s: %w", config.TrustPath(), err)
+		}
+		ui.Successf("✓ Hooks in %s will no longer run", configPath)
+		return nil
+	}
+
+	repoCfg, data, err := config.ReadRepoConfig(repoPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", errors.ErrPathNotFound, configPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	ui.Infof("Hooks in %s:", configPath)
+	printAllHooks(repoCfg.Hooks)
+	fmt.Println()
+
+	if isDryRun(cmd) {
+		fmt.Fprintf(os.Stderr, "[dry-run] trust %s\n", configPath)
+		return nil
+	}
+	if err := config.Trust(repoPath, data); err != nil {
+		return fmt.Errorf("failed to update %s: %w", config.TrustPath(), err)
+	}
+	ui.Successf("✓ Trusted %s; its hooks run until the file changes", configPath)
+	return nil
+}
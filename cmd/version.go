@@ -10,12 +10,17 @@ import (
 // newVersionCmd creates the version command
 func newVersionCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "version",
-		Short: "Show version information",
-		Long:  "Show detailed version information including build details and runtime information.",
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:         "version",
+		Short:       "Show version information",
+		Long:        "Show detailed version information including build details and runtime information.",
+		Annotations: map[string]string{outputAnnotation: "json,yaml"},
+		RunE: func(cmd *cobra.Command, args []string) error {
 			info := version.Get()
+			if machineOutput(cmd) {
+				return render(cmd, info)
+			}
 			fmt.Println(info.String())
+			return nil
 		},
 	}
 
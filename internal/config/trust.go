@@ -0,0 +1,97 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// trustFile records the repositories whose .ccswitch.yaml hooks the user
+// agreed to run, keyed by the main repository's path. Each entry holds the
+// SHA-256 of the file as it was trusted, so editing it revokes the trust.
+type trustFile map[string]string
+
+// TrustPath returns the location of the trust file
+func TrustPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ccswitch", "trust.json")
+}
+
+func loadTrust() (trustFile, error) {
+	trusted := trustFile{}
+	data, err := os.ReadFile(TrustPath())
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", TrustPath(), err)
+	}
+	return trusted, nil
+}
+
+func (t trustFile) save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(TrustPath(), data)
+}
+
+// trustKey identifies a repository however its path was spelled
+func trustKey(repoPath string) string {
+	if abs, err := filepath.Abs(repoPath); err == nil {
+		repoPath = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(repoPath); err == nil {
+		repoPath = resolved
+	}
+	return filepath.Clean(repoPath)
+}
+
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsTrusted reports whether the user trusted the repository's config file
+// with exactly the contents data
+func IsTrusted(repoPath string, data []byte) bool {
+	trusted, err := loadTrust()
+	if err != nil {
+		return false
+	}
+	return trusted[trustKey(repoPath)] == fileHash(data)
+}
+
+// Trust lets the hooks in the repository's config file run, as long as the
+// file holds exactly data, which is what the user looked at
+func Trust(repoPath string, data []byte) error {
+	trusted, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	trusted[trustKey(repoPath)] = fileHash(data)
+	return trusted.save()
+}
+
+// Untrust stops the hooks in the repository's config file from running
+func Untrust(repoPath string) error {
+	trusted, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	key := trustKey(repoPath)
+	if _, ok := trusted[key]; !ok {
+		return nil
+	}
+	delete(trusted, key)
+	return trusted.save()
+}
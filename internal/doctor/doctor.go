@@ -0,0 +1,426 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/lock"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// Kinds of problem
+const (
+	// OrphanDirectory is a directory in the worktree storage git no longer knows about
+	OrphanDirectory = "orphan_directory"
+	// MovedWorktree is a worktree moved without git, so git lists it elsewhere
+	MovedWorktree = "moved_worktree"
+	// MovedRepository is a worktree whose .git file points at a main
+	// repository that is gone, usually because the repository was moved
+	MovedRepository = "moved_repository"
+	// StrandedSession is a session of the repository stored under another
+	// directory of the worktree storage than its ID's, typically the ID it
+	// had before it was moved, so its metadata and trash are kept apart
+	StrandedSession = "stranded_session"
+	// PrunableWorktree is a worktree git lists whose directory is gone
+	PrunableWorktree = "prunable_worktree"
+	// BrokenLink is a worktree whose .git file doesn't point back at its
+	// repository, typically because the repository was moved
+	BrokenLink = "broken_link"
+	// SharedBranch is a branch checked out in more than one worktree
+	SharedBranch = "shared_branch"
+	// ShellWrapper means the shell integration that changes directory is missing
+	ShellWrapper = "shell_wrapper"
+)
+
+// fixOrder is the order problems are reported and fixed in. Links are
+// repaired before pruning, which would otherwise forget a moved worktree,
+// and before stranded sessions are moved, which git refuses while broken.
+var fixOrder = map[string]int{
+	BrokenLink:       0,
+	MovedWorktree:    1,
+	MovedRepository:  2,
+	StrandedSession:  3,
+	PrunableWorktree: 4,
+	OrphanDirectory:  5,
+	SharedBranch:     6,
+	ShellWrapper:     7,
+}
+
+// Severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is something doctor found wrong
+type Problem struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Severity string `json:"severity" yaml:"severity"`
+	// Path is the directory or worktree affected, if any
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Repo is the main repository the problem belongs to, if any
+	Repo    string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Summary string `json:"summary" yaml:"summary"`
+	// Remedy is what Fix does, or what to do by hand when the problem is
+	// not Fixable
+	Remedy  string `json:"remedy" yaml:"remedy"`
+	Fixable bool   `json:"fixable" yaml:"fixable"`
+	// Confirm is set when fixing may throw away files, so the user should
+	// be asked first
+	Confirm bool `json:"confirm" yaml:"confirm"`
+}
+
+// Check diagnoses the repository containing dir (skipped outside a
+// repository), the shared worktree storage and the shell integration,
+// running git through client
+func Check(client git.Client, dir string) ([]Problem, error) {
+	var problems []Problem
+
+	// Worktrees the current repository accounts for, even if broken, so
+	// they aren't also reported as orphans
+	known := make(map[string]bool)
+	if client.IsGitRepository(dir) {
+		if mainRepo, err := client.GetMainRepoPath(dir); err == nil {
+			repoProblems, err := checkRepository(client, mainRepo, known)
+			if err != nil {
+				return nil, err
+			}
+			problems = append(problems, repoProblems...)
+		}
+	}
+
+	storageProblems, err := checkStorage(client, known)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, storageProblems...)
+	problems = append(problems, checkShell()...)
+
+	sort.SliceStable(problems, func(i, j int) bool { return fixOrder[problems[i].Kind] < fixOrder[problems[j].Kind] })
+	return problems, nil
+}
+
+// checkRepository checks the worktrees git lists for a repository
+func checkRepository(client git.Client, mainRepo string, known map[string]bool) ([]Problem, error) {
+	worktrees, err := git.NewWorktreeManager(client, mainRepo).List()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := layout.Root()
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := client.GetRepoID(mainRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	checkedOut := make(map[string][]string)
+	for i, wt := range worktrees {
+		known[canonical(wt.Path)] = true
+
+		// The first entry is the main worktree
+		if i > 0 {
+			if _, statErr := os.Stat(wt.Path); wt.Prunable || statErr != nil {
+				problems = append(problems, Problem{
+					Kind:     PrunableWorktree,
+					Severity: SeverityError,
+					Path:     wt.Path,
+					Repo:     mainRepo,
+					Summary:  fmt.Sprintf("git lists worktree %s, but its directory is gone", wt.Path),
+					Remedy:   "Forget it with 'git worktree prune'",
+					Fixable:  true,
+				})
+				continue
+			}
+
+			if linked, err := git.MainRepoPathFromWorktree(wt.Path); err != nil || canonical(linked) != canonical(mainRepo) {
+				summary := fmt.Sprintf("worktree %s has no usable .git file", wt.Path)
+				if err == nil {
+					summary = fmt.Sprintf("worktree %s points at %s instead of %s", wt.Path, linked, mainRepo)
+				}
+				problems = append(problems, Problem{
+					Kind:     BrokenLink,
+					Severity: SeverityError,
+					Path:     wt.Path,
+					Repo:     mainRepo,
+					Summary:  summary,
+					Remedy:   "Reconnect it with 'git worktree repair'",
+					Fixable:  true,
+				})
+			}
+
+			if dir, name, ok := layout.Stored(wt.Path); ok && dir != repoID {
+				problems = append(problems, Problem{
+					Kind:     StrandedSession,
+					Severity: SeverityError,
+					Path:     wt.Path,
+					Repo:     mainRepo,
+					Summary:  fmt.Sprintf("session %s is stored under %s, not under the repository's ID %s", name, dir, repoID),
+					Remedy:   fmt.Sprintf("Move it to %s with its metadata and trash, as 'ccswitch migrate' does", filepath.Join(root, repoID, name)),
+					Fixable:  true,
+				})
+			}
+		}
+
+		if wt.Branch != "" {
+			checkedOut[wt.Branch] = append(checkedOut[wt.Branch], wt.Path)
+		}
+	}
+
+	var branches []string
+	for branch, paths := range checkedOut {
+		if len(paths) > 1 {
+			branches = append(branches, branch)
+		}
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		paths := checkedOut[branch]
+		problems = append(problems, Problem{
+			Kind:     SharedBranch,
+			Severity: SeverityError,
+			Path:     paths[len(paths)-1],
+			Repo:     mainRepo,
+			Summary:  fmt.Sprintf("branch %s is checked out in %d places: %s", branch, len(paths), strings.Join(paths, ", ")),
+			Remedy:   "Switch all but one of them to another branch, e.g. with 'git switch --detach'",
+		})
+	}
+
+	return problems, nil
+}
+
+// checkStorage looks for directories in the worktree storage that git no
+// longer knows about
+func checkStorage(client git.Client, known map[string]bool) ([]Problem, error) {
+	root, err := layout.Root()
+	if err != nil {
+		return nil, err
+	}
+	repoDirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Worktrees listed by each main repository, read once per repository
+	listed := make(map[string]map[string]bool)
+	isListed := func(mainRepo, path string) bool {
+		if _, ok := listed[mainRepo]; !ok {
+			listed[mainRepo] = make(map[string]bool)
+			if worktrees, err := git.NewWorktreeManager(client, mainRepo).List(); err == nil {
+				for _, wt := range worktrees {
+					listed[mainRepo][canonical(wt.Path)] = true
+				}
+			}
+		}
+		return listed[mainRepo][canonical(path)]
+	}
+
+	var problems []Problem
+	for _, repoDir := range repoDirs {
+		if !repoDir.IsDir() {
+			continue
+		}
+		repoPath := filepath.Join(root, repoDir.Name())
+		sessionDirs, err := os.ReadDir(repoPath)
+		if err != nil {
+			continue
+		}
+		if len(sessionDirs) == 0 {
+			problems = append(problems, orphan(repoPath, "is empty", false))
+			continue
+		}
+
+		for _, sessionDir := range sessionDirs {
+			path := filepath.Join(repoPath, sessionDir.Name())
+			if !sessionDir.IsDir() || known[canonical(path)] {
+				continue
+			}
+			if p, ok := checkStoredWorktree(path, isListed); ok {
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// checkStoredWorktree diagnoses one directory of the worktree storage
+func checkStoredWorktree(path string, isListed func(mainRepo, path string) bool) (Problem, bool) {
+	confirm := hasFiles(path)
+
+	gitDir, err := git.WorktreeGitDir(path)
+	if err != nil {
+		return orphan(path, "is not a git worktree", confirm), true
+	}
+	mainRepo, err := git.MainRepoPathFromWorktree(path)
+	if err != nil {
+		return orphan(path, "is not a git worktree", confirm), true
+	}
+	if _, err := os.Stat(mainRepo); err != nil {
+		// The worktree itself is intact, so it is kept for the repository
+		// to reconnect once doctor runs wherever it went
+		return Problem{
+			Kind:     MovedRepository,
+			Severity: SeverityError,
+			Path:     path,
+			Repo:     mainRepo,
+			Summary:  fmt.Sprintf("worktree %s belongs to %s, which is gone", path, mainRepo),
+			Remedy: "If the repository was moved, run 'ccswitch doctor --fix' in its new location\n" +
+				"If it was deleted, remove the directory by hand",
+		}, true
+	}
+	if _, err := os.Stat(gitDir); err != nil {
+		return orphan(path, fmt.Sprintf("was dropped by %s", mainRepo), confirm), true
+	}
+	if isListed(mainRepo, path) {
+		return Problem{}, false
+	}
+	return Problem{
+		Kind:     MovedWorktree,
+		Severity: SeverityError,
+		Path:     path,
+		Repo:     mainRepo,
+		Summary:  fmt.Sprintf("worktree %s was moved without git, which still expects it elsewhere", path),
+		Remedy:   "Reconnect it with 'git worktree repair'",
+		Fixable:  true,
+	}, true
+}
+
+func orphan(path, reason string, confirm bool) Problem {
+	return Problem{
+		Kind:     OrphanDirectory,
+		Severity: SeverityError,
+		Path:     path,
+		Summary:  fmt.Sprintf("%s %s", path, reason),
+		Remedy:   "Remove the directory",
+		Fixable:  true,
+		Confirm:  confirm,
+	}
+}
+
+// hasFiles reports whether dir holds anything besides a .git file
+func hasFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return true
+	}
+	for _, e := range entries {
+		if e.Name() != ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// rcFiles are where the shell wrapper is usually installed
+var rcFiles = []string{".bashrc", ".bash_profile", ".zshrc", ".profile"}
+
+// checkShell reports a missing shell wrapper, without which ccswitch can't
+// change directory
+func checkShell() []Problem {
+	if utils.IsShellIntegrationActive() {
+		return nil
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	for _, name := range rcFiles {
+		data, err := os.ReadFile(filepath.Join(homeDir, name)) // #nosec G304
+		if err == nil && (strings.Contains(string(data), "ccswitch shell-init") || strings.Contains(string(data), "ccswitch()")) {
+			return []Problem{{
+				Kind:     ShellWrapper,
+				Severity: SeverityWarning,
+				Summary:  fmt.Sprintf("the shell wrapper is set up in ~/%s but not loaded in this shell", name),
+				Remedy:   "Open a new terminal, or source ~/" + name,
+			}}
+		}
+	}
+
+	return []Problem{{
+		Kind:     ShellWrapper,
+		Severity: SeverityWarning,
+		Summary:  "the shell wrapper is not installed, so ccswitch can't change your directory",
+		Remedy:   utils.GetShellIntegrationInstructions(),
+	}}
+}
+
+// Fix repairs a Fixable problem, running git through client
+func Fix(client git.Client, p Problem) error {
+	switch p.Kind {
+	case PrunableWorktree, BrokenLink, MovedWorktree:
+		// Don't pull worktrees out from under a running ccswitch
+		if repoID, err := client.GetRepoID(p.Repo); err == nil {
+			l, err := lock.Acquire(repoID, lock.DefaultTimeout)
+			if err != nil {
+				return err
+			}
+			defer l.Release()
+		}
+
+		wm := git.NewWorktreeManager(client, p.Repo)
+		if p.Kind == PrunableWorktree {
+			return wm.Prune()
+		}
+		return wm.Repair(p.Path)
+
+	case StrandedSession:
+		m := session.NewManagerWithClient(client, p.Repo)
+		if dr, ok := client.Runner().(git.DryRunner); ok {
+			m.SetDryRun(dr.Out)
+		}
+		_, err := m.MigrateSession(p.Path)
+		return err
+
+	case OrphanDirectory:
+		return removeStorageDir(p.Path)
+
+	default:
+		return fmt.Errorf("%s problems can't be fixed automatically", p.Kind)
+	}
+}
+
+// removeStorageDir removes a directory, refusing anything outside the
+// worktree storage
+func removeStorageDir(path string) error {
+	root, err := layout.Root()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(canonical(root), canonical(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("refusing to remove %s, which is outside %s", path, root)
+	}
+	// Never follow a symlink out of the storage
+	if info, err := os.Lstat(path); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("refusing to remove %s, which is not a directory", path)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	// Drop the repository's directory along with its last session
+	if parent := filepath.Dir(path); canonical(parent) != canonical(root) {
+		_ = os.Remove(parent)
+	}
+	return nil
+}
+
+// canonical resolves symlinks so paths can be compared
+func canonical(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
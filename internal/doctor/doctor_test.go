@@ -0,0 +1,231 @@
+package doctor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/session"
+	"github.com/ksred/ccswitch/internal/state"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func kinds(problems []Problem) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range problems {
+		counts[p.Kind]++
+	}
+	return counts
+}
+
+func TestCheckAndFix(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+	t.Setenv("CCSWITCH_SHELL_WRAPPER", "1")
+
+	repo := filepath.Join(tempDir, "api")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "--allow-empty", "-m", "init")
+
+	repoID, err := git.Default().GetRepoID(repo)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+	storage := filepath.Join(tempDir, ".ccswitch", "worktrees", repoID)
+	healthy := filepath.Join(storage, "healthy")
+	deleted := filepath.Join(storage, "deleted")
+	moved := filepath.Join(storage, "moved")
+	runGit(t, repo, "worktree", "add", "-b", "healthy", healthy)
+	runGit(t, repo, "worktree", "add", "-b", "deleted", deleted)
+	runGit(t, repo, "worktree", "add", "-b", "moved", filepath.Join(storage, "before-move"))
+
+	// Break things the ways they break in practice
+	if err := os.RemoveAll(deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(storage, "before-move"), moved); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(storage, "leftover")
+	if err := os.MkdirAll(leftover, 0755); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(storage, "notes")
+	if err := os.MkdirAll(notes, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(notes, "todo.txt"), []byte("keep me"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := Check(git.Default(), repo)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	got := kinds(problems)
+	// Both the deleted worktree and the moved one's old location are prunable
+	if got[PrunableWorktree] != 2 || got[MovedWorktree] != 1 || got[OrphanDirectory] != 2 || len(problems) != 5 {
+		t.Fatalf("Check() = %+v, expected 2 prunable, 1 moved and 2 orphans", problems)
+	}
+
+	for _, p := range problems {
+		if p.Path == notes && !p.Confirm {
+			t.Error("removing a directory that holds files should need confirmation")
+		}
+		if p.Confirm {
+			continue
+		}
+		if err := Fix(git.Default(), p); err != nil {
+			t.Errorf("Fix(%s) failed: %v", p.Summary, err)
+		}
+	}
+
+	problems, err = Check(git.Default(), repo)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Path != notes {
+		t.Errorf("Check() after Fix() = %+v, expected only the unconfirmed directory", problems)
+	}
+	if _, err := os.Stat(filepath.Join(moved, ".git")); err != nil {
+		t.Errorf("moved worktree should be kept: %v", err)
+	}
+	if _, err := os.Stat(healthy); err != nil {
+		t.Errorf("healthy worktree should be untouched: %v", err)
+	}
+}
+
+func TestRemoveStorageDirRefusesOutsidePaths(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	outside := filepath.Join(tempDir, "project")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStorageDir(outside); err == nil {
+		t.Error("removeStorageDir() should refuse a directory outside the worktree storage")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("directory outside the storage was removed: %v", err)
+	}
+}
+
+func TestMovedRepositoryIsRepairedNotRemoved(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+	t.Setenv("CCSWITCH_SHELL_WRAPPER", "1")
+
+	repo := filepath.Join(tempDir, "src", "api")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+	runGit(t, repo, "config", "user.name", "Test")
+	runGit(t, repo, "commit", "--allow-empty", "-m", "init")
+
+	m := session.NewManager(repo)
+	created, err := m.CreateSession(session.CreateOptions{Description: "fix auth"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	spike, err := m.CreateSession(session.CreateOptions{Description: "spike"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	if err := m.RemoveSession(spike.Session.Path, session.RemoveOptions{Branch: spike.Session.Branch, DeleteBranch: true, Force: true}); err != nil {
+		t.Fatalf("RemoveSession() failed: %v", err)
+	}
+	oldID := m.RepoID()
+	// As an older ccswitch left it, without the ID recorded
+	runGit(t, repo, "config", "--unset", git.RepoIDKey)
+
+	moved := filepath.Join(tempDir, "moved-api")
+	if err := os.Rename(repo, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	// Seen from elsewhere the repository is just gone, and the worktree stays
+	problems, err := Check(git.Default(), tempDir)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Kind != MovedRepository || problems[0].Fixable {
+		t.Fatalf("Check() = %+v, expected one moved repository that --fix leaves alone", problems)
+	}
+
+	// From its new location it is reconnected and moved under the new ID
+	problems, err = Check(git.Default(), moved)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	got := kinds(problems)
+	if got[BrokenLink] != 1 || got[StrandedSession] != 1 || len(problems) != 2 {
+		t.Fatalf("Check() in the new location = %+v, expected a broken link and a stranded session", problems)
+	}
+	for _, p := range problems {
+		if err := Fix(git.Default(), p); err != nil {
+			t.Fatalf("Fix(%s) failed: %v", p.Summary, err)
+		}
+	}
+	if problems, _ := Check(git.Default(), moved); len(problems) != 0 {
+		t.Errorf("Check() after Fix() = %+v, expected none", problems)
+	}
+
+	m = session.NewManager(moved)
+	if m.RepoID() == oldID {
+		t.Fatalf("RepoID() = %q, expected moving the repository to change an unrecorded ID", oldID)
+	}
+	sessions, err := m.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	var found *git.SessionInfo
+	for i := range sessions {
+		if sessions[i].Name == created.Session.Name {
+			found = &sessions[i]
+		}
+	}
+	root, _ := layout.Root()
+	if found == nil || found.Path != filepath.Join(root, m.RepoID(), "fix-auth") ||
+		found.Description != "fix auth" || found.BaseCommit != created.Session.BaseCommit {
+		t.Fatalf("ListSessions() = %+v, expected fix-auth under the new ID with its metadata", sessions)
+	}
+	if linked, err := git.MainRepoPathFromWorktree(found.Path); err != nil || linked != moved {
+		t.Errorf("worktree links to %q (%v), expected %q", linked, err, moved)
+	}
+	if oldStore, _ := state.NewStore(oldID); oldStore.Exists() {
+		t.Error("the old ID's state file should be gone once its records moved")
+	}
+	trash, err := m.Trash()
+	if err != nil || len(trash) != 1 || trash[0].Session.Path != filepath.Join(root, m.RepoID(), "spike") {
+		t.Errorf("Trash() = %+v (%v), expected the removed session under the new ID", trash, err)
+	}
+}
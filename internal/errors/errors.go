@@ -15,6 +15,25 @@ var (
 	ErrSessionNotFound    = errors.New("session not found")
 	ErrAlreadyOnBranch    = errors.New("already on branch")
 	ErrNoSessions         = errors.New("no active sessions")
+	ErrRefNotFound        = errors.New("ref not found")
+	ErrHookFailed         = errors.New("hook failed")
+	ErrLocked             = errors.New("another ccswitch is running")
+	ErrInterrupted        = errors.New("interrupted")
+	// ErrRollbackIncomplete means an operation failed and some of what it
+	// had done could not be undone
+	ErrRollbackIncomplete = errors.New("rollback incomplete")
+	// ErrUsage marks a mistake in how a command was invoked, such as an
+	// unknown flag or a missing argument; see Usage
+	ErrUsage = errors.New("invalid usage")
+
+	// Failures git reports, recognized from what it prints
+	ErrBranchCheckedOut = errors.New("branch is checked out in another worktree")
+	ErrBranchNotMerged  = errors.New("branch is not fully merged")
+	ErrInvalidRefName   = errors.New("invalid ref name")
+	ErrWorktreeLocked   = errors.New("worktree is locked")
+	ErrPathNotFound     = errors.New("path not found")
+	ErrDubiousOwnership = errors.New("repository is owned by someone else")
+	ErrNotGitRepository = errors.New("not a git repository")
 )
 
 // Wrap wraps an error with additional context
@@ -25,6 +44,28 @@ func Wrap(err error, message string) error {
 	return fmt.Errorf("%s: %w", message, err)
 }
 
+// usageError is a mistake in how a command was invoked. It keeps the
+// original message, which already says what was wrong.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() []error {
+	return []error{e.err, ErrUsage}
+}
+
+// Usage marks err as a mistake in how a command was invoked
+func Usage(err error) error {
+	if err == nil {
+		return nil
+	}
+	return usageError{err: err}
+}
+
 // IsUncommittedChanges checks if the error is due to uncommitted changes
 func IsUncommittedChanges(err error) bool {
 	return errors.Is(err, ErrUncommittedChanges)
@@ -55,11 +96,84 @@ func IsSessionNotFound(err error) bool {
 	return errors.Is(err, ErrSessionNotFound)
 }
 
+// IsRefNotFound checks if the error is due to a ref that does not resolve
+func IsRefNotFound(err error) bool {
+	return errors.Is(err, ErrRefNotFound)
+}
+
+// IsHookFailed checks if the error is due to a lifecycle hook failing
+func IsHookFailed(err error) bool {
+	return errors.Is(err, ErrHookFailed)
+}
+
+// IsLocked checks if the error is due to another ccswitch holding the repository lock
+func IsLocked(err error) bool {
+	return errors.Is(err, ErrLocked)
+}
+
+// IsInterrupted checks if the error is due to the user interrupting an operation
+func IsInterrupted(err error) bool {
+	return errors.Is(err, ErrInterrupted)
+}
+
+// IsRollbackIncomplete checks if the error is due to a failed operation
+// that could not be fully undone
+func IsRollbackIncomplete(err error) bool {
+	return errors.Is(err, ErrRollbackIncomplete)
+}
+
+// IsBranchCheckedOut checks if the error is due to the branch being
+// checked out in another worktree
+func IsBranchCheckedOut(err error) bool {
+	return errors.Is(err, ErrBranchCheckedOut)
+}
+
+// IsBranchNotMerged checks if the error is due to git refusing to delete an
+// unmerged branch
+func IsBranchNotMerged(err error) bool {
+	return errors.Is(err, ErrBranchNotMerged)
+}
+
+// IsInvalidRefName checks if the error is due to a name git won't accept
+// for a branch or ref
+func IsInvalidRefName(err error) bool {
+	return errors.Is(err, ErrInvalidRefName)
+}
+
+// IsWorktreeLocked checks if the error is due to a locked worktree
+func IsWorktreeLocked(err error) bool {
+	return errors.Is(err, ErrWorktreeLocked)
+}
+
+// IsPathNotFound checks if the error is due to a file or directory that is gone
+func IsPathNotFound(err error) bool {
+	return errors.Is(err, ErrPathNotFound)
+}
+
+// IsDubiousOwnership checks if the error is due to git distrusting a
+// repository owned by another user
+func IsDubiousOwnership(err error) bool {
+	return errors.Is(err, ErrDubiousOwnership)
+}
+
+// IsNotGitRepository checks if the error is due to running outside a repository
+func IsNotGitRepository(err error) bool {
+	return errors.Is(err, ErrNotGitRepository)
+}
+
+// IsUsage checks if the error is due to a command being invoked wrongly
+func IsUsage(err error) bool {
+	return errors.Is(err, ErrUsage)
+}
+
 // ErrorHint provides helpful hints for common errors
 func ErrorHint(err error) string {
 	switch {
+	// Whatever went wrong first, what is left behind matters most
+	case IsRollbackIncomplete(err):
+		return "The next ccswitch run in this repository retries the undo; run 'ccswitch doctor' to see what is left behind"
 	case IsUncommittedChanges(err):
-		return "Use 'git stash' to temporarily save changes"
+		return "Commit, stash or push the work first, or pass --force to throw it away"
 	case IsBranchExists(err):
 		return "Use 'git branch -D <branch>' to delete it first"
 	case IsBranchNotFound(err):
@@ -67,9 +181,33 @@ func ErrorHint(err error) string {
 	case IsWorktreeExists(err):
 		return "Use a different description or remove the existing directory"
 	case IsAlreadyOnBranch(err):
-		return "Switch to main/master branch first, or use a different description"
+		return "Switch to the default branch first, or use a different description"
 	case IsSessionNotFound(err):
 		return "Use 'ccswitch list' to see available sessions"
+	case IsRefNotFound(err):
+		return "Use 'git branch -a' or 'git tag' to see available refs"
+	case IsHookFailed(err):
+		return "Fix the hook in ~/.ccswitch/config.yaml or the repository's .ccswitch.yaml"
+	case IsLocked(err):
+		return "Wait for the other ccswitch to finish, then try again"
+	case IsInterrupted(err):
+		return "Nothing was changed; everything done so far was rolled back"
+	case IsBranchCheckedOut(err):
+		return "Use 'ccswitch list' to find the session that has it, or check out another branch there first"
+	case IsBranchNotMerged(err):
+		return "Merge it first, or pass --force to delete it anyway"
+	case IsInvalidRefName(err):
+		return "Branch names can't contain spaces, '..', '~', '^', ':', '?', '*' or '[', or end in '.lock'"
+	case IsWorktreeLocked(err):
+		return "Run 'git worktree unlock <path>' if nothing needs the lock anymore"
+	case IsPathNotFound(err):
+		return "Run 'ccswitch doctor' to find sessions whose directories are gone"
+	case IsDubiousOwnership(err):
+		return "If you trust the repository, run 'git config --global --add safe.directory <path>'"
+	case IsNotGitRepository(err):
+		return "Run ccswitch from inside a git repository"
+	case IsUsage(err):
+		return "Run the command with --help to see how to use it"
 	default:
 		return ""
 	}
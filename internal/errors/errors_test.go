@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -64,6 +65,35 @@ func TestErrorCheckers(t *testing.T) {
 
 		{"IsSessionNotFound true", ErrSessionNotFound, IsSessionNotFound, true},
 		{"IsSessionNotFound false", ErrBranchNotFound, IsSessionNotFound, false},
+
+		{"IsRefNotFound true", ErrRefNotFound, IsRefNotFound, true},
+		{"IsRefNotFound false", ErrBranchNotFound, IsRefNotFound, false},
+
+		{"IsHookFailed true", Wrap(ErrHookFailed, "pre_create"), IsHookFailed, true},
+		{"IsHookFailed false", ErrRefNotFound, IsHookFailed, false},
+		{"IsLocked true", Wrap(ErrLocked, "create"), IsLocked, true},
+		{"IsLocked false", ErrHookFailed, IsLocked, false},
+		{"IsInterrupted true", Wrap(ErrInterrupted, "create"), IsInterrupted, true},
+		{"IsInterrupted false", ErrLocked, IsInterrupted, false},
+		{"IsRollbackIncomplete true", fmt.Errorf("%w (%w: boom)", ErrInterrupted, ErrRollbackIncomplete), IsRollbackIncomplete, true},
+		{"IsRollbackIncomplete false", ErrInterrupted, IsRollbackIncomplete, false},
+
+		{"IsBranchCheckedOut true", Wrap(ErrBranchCheckedOut, "checkout"), IsBranchCheckedOut, true},
+		{"IsBranchCheckedOut false", ErrBranchExists, IsBranchCheckedOut, false},
+		{"IsBranchNotMerged true", ErrBranchNotMerged, IsBranchNotMerged, true},
+		{"IsBranchNotMerged false", ErrBranchNotFound, IsBranchNotMerged, false},
+		{"IsInvalidRefName true", ErrInvalidRefName, IsInvalidRefName, true},
+		{"IsInvalidRefName false", ErrRefNotFound, IsInvalidRefName, false},
+		{"IsWorktreeLocked true", ErrWorktreeLocked, IsWorktreeLocked, true},
+		{"IsWorktreeLocked false", ErrLocked, IsWorktreeLocked, false},
+		{"IsPathNotFound true", ErrPathNotFound, IsPathNotFound, true},
+		{"IsPathNotFound false", ErrWorktreeNotFound, IsPathNotFound, false},
+		{"IsDubiousOwnership true", ErrDubiousOwnership, IsDubiousOwnership, true},
+		{"IsDubiousOwnership false", ErrNotGitRepository, IsDubiousOwnership, false},
+		{"IsNotGitRepository true", ErrNotGitRepository, IsNotGitRepository, true},
+		{"IsNotGitRepository false", ErrDubiousOwnership, IsNotGitRepository, false},
+		{"IsUsage true", Usage(errors.New("unknown flag: --nope")), IsUsage, true},
+		{"IsUsage false", ErrSessionNotFound, IsUsage, false},
 	}
 
 	for _, tt := range tests {
@@ -85,7 +115,7 @@ func TestErrorHint(t *testing.T) {
 		{
 			name: "uncommitted changes hint",
 			err:  ErrUncommittedChanges,
-			want: "Use 'git stash' to temporarily save changes",
+			want: "Commit, stash or push the work first, or pass --force to throw it away",
 		},
 		{
 			name: "branch exists hint",
@@ -100,22 +130,57 @@ func TestErrorHint(t *testing.T) {
 		{
 			name: "already on branch hint",
 			err:  ErrAlreadyOnBranch,
-			want: "Switch to main/master branch first, or use a different description",
+			want: "Switch to the default branch first, or use a different description",
 		},
 		{
 			name: "session not found hint",
 			err:  ErrSessionNotFound,
 			want: "Use 'ccswitch list' to see available sessions",
 		},
+		{
+			name: "ref not found hint",
+			err:  ErrRefNotFound,
+			want: "Use 'git branch -a' or 'git tag' to see available refs",
+		},
+		{
+			name: "locked hint",
+			err:  ErrLocked,
+			want: "Wait for the other ccswitch to finish, then try again",
+		},
+		{
+			name: "interrupted hint",
+			err:  ErrInterrupted,
+			want: "Nothing was changed; everything done so far was rolled back",
+		},
+		{
+			name: "interrupted with incomplete rollback hint",
+			err:  fmt.Errorf("%w (%w: boom)", ErrInterrupted, ErrRollbackIncomplete),
+			want: "The next ccswitch run in this repository retries the undo; run 'ccswitch doctor' to see what is left behind",
+		},
 		{
 			name: "unknown error no hint",
 			err:  errors.New("unknown error"),
 			want: "",
 		},
+		{
+			name: "branch checked out hint",
+			err:  ErrBranchCheckedOut,
+			want: "Use 'ccswitch list' to find the session that has it, or check out another branch there first",
+		},
+		{
+			name: "dubious ownership hint",
+			err:  ErrDubiousOwnership,
+			want: "If you trust the repository, run 'git config --global --add safe.directory <path>'",
+		},
+		{
+			name: "usage hint",
+			err:  Usage(errors.New("accepts 1 arg(s), received 0")),
+			want: "Run the command with --help to see how to use it",
+		},
 		{
 			name: "wrapped error preserves hint",
 			err:  Wrap(ErrUncommittedChanges, "context"),
-			want: "Use 'git stash' to temporarily save changes",
+			want: "Commit, stash or push the work first, or pass --force to throw it away",
 		},
 	}
 
@@ -140,6 +205,19 @@ func TestErrorConstants(t *testing.T) {
 		ErrSessionNotFound,
 		ErrAlreadyOnBranch,
 		ErrNoSessions,
+		ErrRefNotFound,
+		ErrHookFailed,
+		ErrLocked,
+		ErrInterrupted,
+		ErrRollbackIncomplete,
+		ErrBranchCheckedOut,
+		ErrBranchNotMerged,
+		ErrInvalidRefName,
+		ErrWorktreeLocked,
+		ErrPathNotFound,
+		ErrDubiousOwnership,
+		ErrNotGitRepository,
+		ErrUsage,
 	}
 
 	seen := make(map[string]bool)
@@ -155,3 +233,51 @@ func TestErrorConstants(t *testing.T) {
 		}
 	}
 }
+
+func TestUsage(t *testing.T) {
+	if Usage(nil) != nil {
+		t.Error("Usage(nil) should be nil")
+	}
+	err := Usage(errors.New("unknown flag: --nope"))
+	if err.Error() != "unknown flag: --nope" {
+		t.Errorf("Usage() message = %q, expected the original", err.Error())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+		kind string
+	}{
+		{nil, ExitOK, "error"},
+		{errors.New("something broke"), ExitFailure, "error"},
+		{Usage(errors.New("unknown flag: --nope")), ExitUsage, "usage"},
+		{ErrNotGitRepository, ExitNotRepository, "not_git_repository"},
+		{Wrap(ErrSessionNotFound, "nope"), ExitSessionMissing, "session_not_found"},
+		{ErrBranchNotFound, ExitRefMissing, "branch_not_found"},
+		{ErrPathNotFound, ExitPathMissing, "path_not_found"},
+		{ErrWorktreeExists, ExitExists, "worktree_exists"},
+		{ErrBranchCheckedOut, ExitBranchInUse, "branch_checked_out"},
+		{ErrUncommittedChanges, ExitUnsavedWork, "uncommitted_changes"},
+		{ErrHookFailed, ExitHookFailed, "hook_failed"},
+		{ErrLocked, ExitLocked, "locked"},
+		{ErrInvalidRefName, ExitInvalidName, "invalid_ref_name"},
+		// An interrupted step fails with whatever cancelling it caused
+		{fmt.Errorf("%w: %w", ErrInterrupted, ErrWorktreeExists), ExitInterrupted, "interrupted"},
+		// Unless rolling it back failed
+		{fmt.Errorf("%w (%w: boom)", ErrInterrupted, ErrRollbackIncomplete), ExitRollbackIncomplete, "rollback_incomplete"},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.code {
+			t.Errorf("ExitCode(%v) = %d, expected %d", tt.err, got, tt.code)
+		}
+		if tt.err == nil {
+			continue
+		}
+		if got := Kind(tt.err); got != tt.kind {
+			t.Errorf("Kind(%v) = %q, expected %q", tt.err, got, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"errors"
+)
+
+// Exit codes ccswitch ends with, so scripts can tell failures apart. They
+// are documented in the README; never renumber one.
+const (
+	ExitOK             = 0
+	ExitFailure        = 1
+	ExitUsage          = 2
+	ExitNotRepository  = 3
+	ExitSessionMissing = 4
+	ExitRefMissing     = 5
+	ExitPathMissing    = 6
+	ExitExists         = 7
+	ExitBranchInUse    = 8
+	ExitUnsavedWork    = 9
+	ExitHookFailed     = 10
+	ExitLocked         = 11
+	ExitInvalidName    = 12
+	// ExitRollbackIncomplete means a failed operation left changes behind
+	ExitRollbackIncomplete = 13
+	// ExitInterrupted is what shells report for a process stopped by Ctrl+C
+	ExitInterrupted = 130
+)
+
+// exitKinds maps sentinels to their exit code and the name JSON errors give
+// it. The first match wins, so more telling kinds come first.
+var exitKinds = []struct {
+	err  error
+	code int
+	name string
+}{
+	{ErrRollbackIncomplete, ExitRollbackIncomplete, "rollback_incomplete"},
+	{ErrInterrupted, ExitInterrupted, "interrupted"},
+	{ErrUsage, ExitUsage, "usage"},
+	{ErrNotGitRepository, ExitNotRepository, "not_git_repository"},
+	{ErrDubiousOwnership, ExitNotRepository, "dubious_ownership"},
+	{ErrLocked, ExitLocked, "locked"},
+	{ErrWorktreeLocked, ExitLocked, "worktree_locked"},
+	{ErrHookFailed, ExitHookFailed, "hook_failed"},
+	{ErrUncommittedChanges, ExitUnsavedWork, "uncommitted_changes"},
+	{ErrBranchNotMerged, ExitUnsavedWork, "branch_not_merged"},
+	{ErrAlreadyOnBranch, ExitBranchInUse, "already_on_branch"},
+	{ErrBranchCheckedOut, ExitBranchInUse, "branch_checked_out"},
+	{ErrBranchExists, ExitExists, "branch_exists"},
+	{ErrWorktreeExists, ExitExists, "worktree_exists"},
+	{ErrSessionNotFound, ExitSessionMissing, "session_not_found"},
+	{ErrNoSessions, ExitSessionMissing, "no_sessions"},
+	{ErrBranchNotFound, ExitRefMissing, "branch_not_found"},
+	{ErrRefNotFound, ExitRefMissing, "ref_not_found"},
+	{ErrWorktreeNotFound, ExitPathMissing, "worktree_not_found"},
+	{ErrPathNotFound, ExitPathMissing, "path_not_found"},
+	{ErrInvalidRefName, ExitInvalidName, "invalid_ref_name"},
+}
+
+// ExitCode returns the exit code ccswitch ends with after err: ExitOK for
+// nil, the code of the first sentinel err matches, or ExitFailure
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for _, k := range exitKinds {
+		if errors.Is(err, k.err) {
+			return k.code
+		}
+	}
+	return ExitFailure
+}
+
+// Kind names the sentinel err matches, for machine-readable errors, or
+// returns "error" when it matches none
+func Kind(err error) string {
+	for _, k := range exitKinds {
+		if errors.Is(err, k.err) {
+			return k.name
+		}
+	}
+	return "error"
+}
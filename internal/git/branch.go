@@ -2,27 +2,29 @@ package git
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
 // BranchManager handles git branch operations
 type BranchManager struct {
+	git      Client
 	repoPath string
 }
 
-// NewBranchManager creates a new BranchManager
-func NewBranchManager(repoPath string) *BranchManager {
-	return &BranchManager{repoPath: repoPath}
+// NewBranchManager creates a new BranchManager that runs git through client
+func NewBranchManager(client Client, repoPath string) *BranchManager {
+	return &BranchManager{git: client, repoPath: repoPath}
 }
 
-// Create creates a new branch
-func (bm *BranchManager) Create(name string) error {
-	cmd := exec.Command("git", "branch", name)
-	cmd.Dir = bm.repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create branch: %w, output: %s", err, string(output))
+// Create creates a new branch starting at startPoint, or at HEAD when
+// startPoint is empty. The new branch never tracks its start point.
+func (bm *BranchManager) Create(name, startPoint string) error {
+	args := []string{"branch", "--no-track", name}
+	if startPoint != "" {
+		args = append(args, startPoint)
+	}
+	if _, err := bm.git.Run(bm.repoPath, args...); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
 	}
 	return nil
 }
@@ -33,28 +35,39 @@ func (bm *BranchManager) Delete(name string, force bool) error {
 	if force {
 		flag = "-D"
 	}
-	cmd := exec.Command("git", "branch", flag, name)
-	cmd.Dir = bm.repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to delete branch: %w, output: %s", err, string(output))
+	if _, err := bm.git.Run(bm.repoPath, "branch", flag, name); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
 	}
 	return nil
 }
 
+// UniqueCommits lists the commits, as "<short hash> <subject>", that are
+// reachable from the branch but from no other branch, remote branch or
+// tag. Deleting the branch loses them. (--exclude patterns for --branches
+// leave out the refs/heads/ prefix.)
+func (bm *BranchManager) UniqueCommits(name string) ([]string, error) {
+	output, err := bm.git.Run(bm.repoPath, "log", "--format=%h %s", "refs/heads/"+name, "--not",
+		"--exclude="+name, "--branches", "--remotes", "--tags")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits of %s: %w", name, err)
+	}
+	return splitLines(string(output)), nil
+}
+
 // Exists checks if a branch exists
 func (bm *BranchManager) Exists(name string) bool {
-	cmd := exec.Command("git", "rev-parse", "--verify", "refs/heads/"+name) // #nosec G204
-	cmd.Dir = bm.repoPath
-	output, err := cmd.CombinedOutput()
+	return bm.RefExists("refs/heads/" + name)
+}
+
+// RefExists checks if a fully qualified ref exists
+func (bm *BranchManager) RefExists(ref string) bool {
+	output, err := bm.git.Run(bm.repoPath, "rev-parse", "--verify", ref)
 	return err == nil && strings.TrimSpace(string(output)) != ""
 }
 
 // GetCurrent returns the current branch name
 func (bm *BranchManager) GetCurrent() (string, error) {
-	cmd := exec.Command("git", "branch", "--show-current")
-	cmd.Dir = bm.repoPath
-	output, err := cmd.CombinedOutput()
+	output, err := bm.git.Run(bm.repoPath, "branch", "--show-current")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -63,8 +76,61 @@ func (bm *BranchManager) GetCurrent() (string, error) {
 
 // HasUncommittedChanges checks if there are uncommitted changes
 func (bm *BranchManager) HasUncommittedChanges() bool {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = bm.repoPath
-	output, err := cmd.CombinedOutput()
+	output, err := bm.git.Run(bm.repoPath, "status", "--porcelain")
 	return err == nil && strings.TrimSpace(string(output)) != ""
 }
+
+// ResolveCommit returns the commit SHA a ref points at
+func (bm *BranchManager) ResolveCommit(ref string) (string, error) {
+	output, err := bm.git.Run(bm.repoPath, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Rename renames a branch. git moves the branch's config section along with
+// it, so upstream tracking is preserved.
+func (bm *BranchManager) Rename(oldName, newName string) error {
+	if _, err := bm.git.Run(bm.repoPath, "branch", "-m", oldName, newName); err != nil {
+		return fmt.Errorf("failed to rename branch: %w", err)
+	}
+	return nil
+}
+
+// Tracking returns the remote and merge ref a branch tracks, both empty when
+// it tracks nothing
+func (bm *BranchManager) Tracking(name string) (remote, merge string) {
+	get := func(key string) string {
+		output, _ := bm.git.Run(bm.repoPath, "config", "--get", "branch."+name+"."+key)
+		return strings.TrimSpace(string(output))
+	}
+	return get("remote"), get("merge")
+}
+
+// SetTracking makes a branch track merge on remote, as Tracking returns
+// them, whether or not that remote branch still exists
+func (bm *BranchManager) SetTracking(name, remote, merge string) error {
+	for key, value := range map[string]string{"remote": remote, "merge": merge} {
+		if _, err := bm.git.Run(bm.repoPath, "config", "branch."+name+"."+key, value); err != nil {
+			return fmt.Errorf("failed to set upstream of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// UpdateRef points a fully qualified ref at commit, creating it if needed
+func (bm *BranchManager) UpdateRef(ref, commit string) error {
+	if _, err := bm.git.Run(bm.repoPath, "update-ref", ref, commit); err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+	return nil
+}
+
+// DeleteRef deletes a fully qualified ref
+func (bm *BranchManager) DeleteRef(ref string) error {
+	if _, err := bm.git.Run(bm.repoPath, "update-ref", "-d", ref); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ref, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"strings"
+)
+
+// defaultBranchGuesses are tried, in order, when nothing says which branch
+// is the default
+var defaultBranchGuesses = []string{"main", "master", "trunk", "develop"}
+
+// DefaultBranch resolves the default branch of the repository at repoPath.
+// The configured name wins; otherwise it is the branch origin/HEAD points
+// at, then init.defaultBranch if such a branch exists, then the first of
+// main, master, trunk and develop that exists locally or on origin. With
+// nothing to go on it guesses init.defaultBranch, or main.
+func (c Client) DefaultBranch(repoPath, configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	bm := NewBranchManager(c, repoPath)
+	if output, err := c.Run(repoPath, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if branch, ok := strings.CutPrefix(strings.TrimSpace(string(output)), "origin/"); ok && branch != "" {
+			return branch
+		}
+	}
+
+	exists := func(branch string) bool {
+		return bm.Exists(branch) || bm.RefExists("refs/remotes/origin/"+branch)
+	}
+
+	output, _ := c.Run(repoPath, "config", "--get", "init.defaultBranch")
+	initDefault := strings.TrimSpace(string(output))
+	if initDefault != "" && exists(initDefault) {
+		return initDefault
+	}
+
+	for _, branch := range defaultBranchGuesses {
+		if exists(branch) {
+			return branch
+		}
+	}
+
+	if initDefault != "" {
+		return initDefault
+	}
+	return defaultBranchGuesses[0]
+}
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DryRunner runs the commands that only look at a repository through
+// Runner, and writes the ones that would change something to Out instead
+// of running them. Those succeed without output.
+type DryRunner struct {
+	Runner Runner
+	Out    io.Writer
+}
+
+// Run implements Runner
+func (r DryRunner) Run(ctx context.Context, c Command) ([]byte, error) {
+	if IsReadOnly(c.Args) {
+		return r.Runner.Run(ctx, c)
+	}
+	fmt.Fprintf(r.Out, "[dry-run] %s\n", c)
+	return nil, nil
+}
+
+// readOnlyCommands never change a repository, whatever their arguments
+var readOnlyCommands = map[string]bool{
+	"cat-file":     true,
+	"cherry":       true,
+	"diff":         true,
+	"for-each-ref": true,
+	"log":          true,
+	"ls-files":     true,
+	"merge-base":   true,
+	"rev-list":     true,
+	"rev-parse":    true,
+	"show":         true,
+	"show-ref":     true,
+	"status":       true,
+	// commit-tree only writes an unreferenced object, for SquashMerged
+	"commit-tree": true,
+}
+
+// IsReadOnly reports whether git with args only looks at a repository.
+// Commands it doesn't know are assumed to change something.
+func IsReadOnly(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	rest := args[1:]
+	switch args[0] {
+	case "branch":
+		return len(rest) == 1 && rest[0] == "--show-current"
+	case "config":
+		return len(rest) > 0 && strings.HasPrefix(rest[0], "--get")
+	case "remote":
+		return len(rest) == 0 || rest[0] == "-v" || rest[0] == "get-url"
+	case "worktree":
+		return len(rest) > 0 && rest[0] == "list"
+	case "symbolic-ref":
+		// With a second argument it points the ref somewhere new
+		return len(nonFlags(rest)) <= 1
+	default:
+		return readOnlyCommands[args[0]]
+	}
+}
+
+func nonFlags(args []string) []string {
+	var found []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			found = append(found, arg)
+		}
+	}
+	return found
+}
+
+// DryRun returns a copy of the client that only runs commands that look at
+// a repository, writing the others to out; see DryRunner
+func (c Client) DryRun(out io.Writer) Client {
+	if _, ok := c.Runner().(DryRunner); ok {
+		return c
+	}
+	return NewClient(c.Context(), DryRunner{Runner: c.Runner(), Out: out})
+}
+
+// IsDryRun reports whether the client leaves out commands that change a
+// repository
+func (c Client) IsDryRun() bool {
+	_, ok := c.Runner().(DryRunner)
+	return ok
+}
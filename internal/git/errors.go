@@ -0,0 +1,75 @@
+package git
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/errors"
+)
+
+// stderrKinds maps what git prints when it fails to the error it means,
+// most specific first. Patterns are matched against lowercased stderr; a
+// command restricts a pattern to one git subcommand.
+var stderrKinds = []struct {
+	pattern string
+	command string
+	kind    error
+}{
+	{pattern: "detected dubious ownership", kind: errors.ErrDubiousOwnership},
+	{pattern: "not a git repository", kind: errors.ErrNotGitRepository},
+	// "is already checked out at" before git 2.42, "is already used by
+	// worktree at" since; branch -d says "checked out at" too
+	{pattern: "checked out at", kind: errors.ErrBranchCheckedOut},
+	{pattern: "used by worktree at", kind: errors.ErrBranchCheckedOut},
+	{pattern: "is not fully merged", kind: errors.ErrBranchNotMerged},
+	{pattern: "is not a valid branch name", kind: errors.ErrInvalidRefName},
+	{pattern: "with bad name", kind: errors.ErrInvalidRefName},
+	{pattern: "locked working tree", kind: errors.ErrWorktreeLocked},
+	{pattern: "is not a working tree", kind: errors.ErrPathNotFound},
+	{pattern: "no such file or directory", kind: errors.ErrPathNotFound},
+	{pattern: "a branch named", kind: errors.ErrBranchExists},
+	{pattern: "already exists", command: "worktree", kind: errors.ErrWorktreeExists},
+	{pattern: "not a valid object name", kind: errors.ErrRefNotFound},
+	{pattern: "unknown revision", kind: errors.ErrRefNotFound},
+	{pattern: "needed a single revision", kind: errors.ErrRefNotFound},
+	{pattern: "invalid reference", kind: errors.ErrRefNotFound},
+}
+
+// classify returns the internal/errors sentinel for a failed git command,
+// judging by its stderr, or nil when the failure isn't one ccswitch knows.
+// err is what running it returned.
+func classify(args []string, stderr string, err error) error {
+	if os.IsNotExist(err) {
+		// git never started: the directory to run it in is gone
+		return errors.ErrPathNotFound
+	}
+	stderr = strings.ToLower(stderr)
+	for _, k := range stderrKinds {
+		if k.command != "" && (len(args) == 0 || args[0] != k.command) {
+			continue
+		}
+		if strings.Contains(stderr, k.pattern) {
+			return k.kind
+		}
+	}
+	return nil
+}
+
+// stderrMessage picks what git said went wrong out of its stderr: the first
+// "fatal:" or "error:" line without that prefix, or else the first line
+// that isn't a hint
+func stderrMessage(stderr string) string {
+	var first string
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"fatal: ", "error: "} {
+			if message, ok := strings.CutPrefix(line, prefix); ok {
+				return strings.TrimRight(message, ";")
+			}
+		}
+		if first == "" && line != "" && !strings.HasPrefix(line, "hint: ") {
+			first = line
+		}
+	}
+	return first
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/errors"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		args   string
+		stderr string
+		want   error
+	}{
+		{"worktree add", "fatal: 'main' is already checked out at '/src/api'", errors.ErrBranchCheckedOut},
+		{"worktree add", "fatal: 'main' is already used by worktree at '/src/api'", errors.ErrBranchCheckedOut},
+		{"branch -d", "error: Cannot delete branch 'x' checked out at '/src/x'", errors.ErrBranchCheckedOut},
+		{"branch -d", "error: The branch 'x' is not fully merged.", errors.ErrBranchNotMerged},
+		{"branch", "fatal: 'bad..name' is not a valid branch name", errors.ErrInvalidRefName},
+		{"update-ref", "fatal: update_ref failed for ref 'refs/x..y': refusing to update ref with bad name 'refs/x..y'", errors.ErrInvalidRefName},
+		{"worktree remove", "fatal: cannot remove a locked working tree;\nuse 'remove -f -f' to override or unlock first", errors.ErrWorktreeLocked},
+		{"worktree remove", "fatal: '/tmp/nope' is not a working tree", errors.ErrPathNotFound},
+		{"status", "fatal: detected dubious ownership in repository at '/srv/api'", errors.ErrDubiousOwnership},
+		{"status", "fatal: not a git repository (or any of the parent directories): .git", errors.ErrNotGitRepository},
+		{"branch", "fatal: a branch named 'main' already exists", errors.ErrBranchExists},
+		{"worktree add", "fatal: '../api-x' already exists", errors.ErrWorktreeExists},
+		{"branch", "fatal: not a valid object name: 'nope'", errors.ErrRefNotFound},
+		{"rebase", "error: could not apply 1234567... Change app", nil},
+	}
+
+	for _, tt := range tests {
+		if got := classify(strings.Fields(tt.args), tt.stderr, nil); got != tt.want {
+			t.Errorf("classify(%q, %q) = %v, expected %v", tt.args, tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestCommandErrorMessage(t *testing.T) {
+	err := NewCommandError(Command{Args: []string{"worktree", "remove", "/tmp/x"}}, 128,
+		"fatal: cannot remove a locked working tree;\nuse 'remove -f -f' to override or unlock first\n", nil)
+	if got := err.Error(); got != "cannot remove a locked working tree" {
+		t.Errorf("Error() = %q, expected git's message", got)
+	}
+	if !errors.IsWorktreeLocked(err) {
+		t.Error("IsWorktreeLocked() = false for a locked worktree")
+	}
+}
+
+func TestExecRunnerClassifies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	client := NewClient(context.Background(), nil)
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "-b", "main"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "--quiet", "--allow-empty", "-m", "Initial commit"},
+	} {
+		if _, err := client.Run(repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	wm := NewWorktreeManager(client, repo)
+	if err := wm.Create(filepath.Join(t.TempDir(), "wt"), "main"); !errors.IsBranchCheckedOut(err) {
+		t.Errorf("Create() of a checked out branch = %v, expected ErrBranchCheckedOut", err)
+	}
+
+	bm := NewBranchManager(client, repo)
+	if err := bm.Create("bad..name", ""); !errors.IsInvalidRefName(err) {
+		t.Errorf("Create() of a bad branch name = %v, expected ErrInvalidRefName", err)
+	}
+
+	if _, err := client.Run(filepath.Join(repo, "gone"), "status"); !errors.IsPathNotFound(err) {
+		t.Errorf("running in a missing directory = %v, expected ErrPathNotFound", err)
+	}
+}
@@ -0,0 +1,92 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeRunner is an in-memory Runner for tests. It answers commands from
+// responses registered with On and Fail, matched by their arguments, and
+// records every command it is asked to run. Registering more than one
+// response for the same arguments plays them in turn, repeating the last,
+// so a ref can be missing until a later command creates it.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses map[string][]fakeResponse
+	calls     []Command
+}
+
+type fakeResponse struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// NewFakeRunner creates a FakeRunner without any responses
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{responses: make(map[string][]fakeResponse)}
+}
+
+// On makes commands with exactly these arguments succeed, printing stdout
+func (f *FakeRunner) On(args string, stdout string) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[args] = append(f.responses[args], fakeResponse{stdout: stdout})
+	return f
+}
+
+// Fail makes commands with exactly these arguments exit with exitCode,
+// printing stderr
+func (f *FakeRunner) Fail(args string, exitCode int, stderr string) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[args] = append(f.responses[args], fakeResponse{stderr: stderr, exitCode: exitCode})
+	return f
+}
+
+// Calls returns the commands run so far, oldest first
+func (f *FakeRunner) Calls() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Command{}, f.calls...)
+}
+
+// Ran reports whether a command with exactly these arguments was run
+func (f *FakeRunner) Ran(args string) bool {
+	for _, c := range f.Calls() {
+		if strings.Join(c.Args, " ") == args {
+			return true
+		}
+	}
+	return false
+}
+
+// Run implements Runner. Commands without a response fail with exit code
+// 128, as git does for most usage errors.
+func (f *FakeRunner) Run(ctx context.Context, c Command) ([]byte, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	key := strings.Join(c.Args, " ")
+	queue, ok := f.responses[key]
+	var resp fakeResponse
+	if ok {
+		resp = queue[0]
+		if len(queue) > 1 {
+			f.responses[key] = queue[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, NewCommandError(c, -1, "", err)
+	}
+	if !ok {
+		resp = fakeResponse{stderr: "fake: no response for " + c.String(), exitCode: 128}
+	}
+	if resp.exitCode != 0 {
+		return nil, NewCommandError(c, resp.exitCode, resp.stderr, fmt.Errorf("exit status %d", resp.exitCode))
+	}
+	return []byte(resp.stdout), nil
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FetchTimeout bounds a fetch, so an unreachable remote can't stall a
+// command for long
+const FetchTimeout = 30 * time.Second
+
+// Fetch fetches every remote of the repository at repoPath, pruning remote
+// branches that were deleted
+func (c Client) Fetch(repoPath string) error {
+	return c.fetch(repoPath, "--all")
+}
+
+// FetchRemote fetches one remote of the repository at repoPath, pruning
+// remote branches that were deleted
+func (c Client) FetchRemote(repoPath, remote string) error {
+	return c.fetch(repoPath, remote)
+}
+
+func (c Client) fetch(repoPath, what string) error {
+	ctx, cancel := context.WithTimeout(c.Context(), FetchTimeout)
+	defer cancel()
+
+	_, err := c.Runner().Run(ctx, Command{
+		Dir:  repoPath,
+		Args: []string{"fetch", "--prune", "--quiet", what},
+		// Fail rather than wait for credentials nobody is there to type
+		Env: []string{"GIT_TERMINAL_PROMPT=0"},
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to fetch %s: timed out after %s", what, FetchTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", what, err)
+	}
+	return nil
+}
+
+// Remotes lists the remotes of the repository at repoPath
+func (c Client) Remotes(repoPath string) []string {
+	output, err := c.Run(repoPath, "remote")
+	if err != nil {
+		return nil
+	}
+	return splitLines(string(output))
+}
@@ -0,0 +1,51 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff returns the uncommitted changes of the worktree at path as a patch
+// git apply can replay, binary files included: the staged changes if staged
+// is set, otherwise the changes not yet staged. Prefixes are given
+// explicitly so diff.noprefix can't break the patch.
+func (c Client) Diff(path string, staged bool) ([]byte, error) {
+	args := []string{"diff", "--binary", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	output, err := c.Run(path, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// ApplyPatch applies a patch made by Diff to the worktree at path, and to
+// its index as well if index is set
+func (c Client) ApplyPatch(path string, patch []byte, index bool) error {
+	args := []string{"apply"}
+	if index {
+		args = append(args, "--index")
+	}
+	if _, err := c.RunCommand(Command{Dir: path, Args: args, Stdin: patch}); err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return nil
+}
+
+// UntrackedFiles lists the untracked files of the worktree at path, leaving
+// out ignored ones. Paths are relative and unquoted.
+func (c Client) UntrackedFiles(path string) ([]string, error) {
+	output, err := c.Run(path, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files of %s: %w", path, err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"fmt"
+)
+
+// IsAncestor reports whether commit ancestor is reachable from descendant
+func (c Client) IsAncestor(path, ancestor, descendant string) bool {
+	_, err := c.Run(path, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// Rebase rebases the branch checked out in the worktree at path onto ref.
+// On a conflict git stops and leaves the rebase in progress.
+func (c Client) Rebase(path, ref string) error {
+	if _, err := c.Run(path, "rebase", ref); err != nil {
+		return fmt.Errorf("failed to rebase onto %s: %w", ref, err)
+	}
+	return nil
+}
+
+// Merge merges ref into the branch checked out in the worktree at path. On
+// a conflict git stops and leaves the merge in progress.
+func (c Client) Merge(path, ref string) error {
+	if _, err := c.Run(path, "merge", "--no-edit", ref); err != nil {
+		return fmt.Errorf("failed to merge %s: %w", ref, err)
+	}
+	return nil
+}
+
+// AbortRebase gives up a rebase in progress, restoring the branch
+func (c Client) AbortRebase(path string) error {
+	if _, err := c.Run(path, "rebase", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort rebase: %w", err)
+	}
+	return nil
+}
+
+// AbortMerge gives up a merge in progress, restoring the branch
+func (c Client) AbortMerge(path string) error {
+	if _, err := c.Run(path, "merge", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort merge: %w", err)
+	}
+	return nil
+}
+
+// ConflictedFiles lists the files of the worktree at path with unresolved
+// conflicts
+func (c Client) ConflictedFiles(path string) ([]string, error) {
+	output, err := c.Run(path, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicts: %w", err)
+	}
+	return splitLines(string(output)), nil
+}
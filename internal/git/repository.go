@@ -1,17 +1,17 @@
 package git
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
 // GetRepoName returns the repository name from the current directory
-func GetRepoName(dir string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+func (c Client) GetRepoName(dir string) (string, error) {
+	output, err := c.Run(dir, "rev-parse", "--show-toplevel")
 	if err != nil {
 		return "", err
 	}
@@ -20,11 +20,9 @@ func GetRepoName(dir string) (string, error) {
 }
 
 // GetMainRepoPath returns the path to the main repository (not worktree)
-func GetMainRepoPath(dir string) (string, error) {
+func (c Client) GetMainRepoPath(dir string) (string, error) {
 	// First get the common git directory
-	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+	output, err := c.Run(dir, "rev-parse", "--git-common-dir")
 	if err != nil {
 		return "", err
 	}
@@ -32,15 +30,18 @@ func GetMainRepoPath(dir string) (string, error) {
 
 	// If gitDir is just ".git", we're in the main repo already
 	if gitDir == ".git" {
-		cmd = exec.Command("git", "rev-parse", "--show-toplevel")
-		cmd.Dir = dir
-		output, err = cmd.CombinedOutput()
+		output, err = c.Run(dir, "rev-parse", "--show-toplevel")
 		if err != nil {
 			return "", err
 		}
 		return strings.TrimSpace(string(output)), nil
 	}
 
+	// git reports the common dir relative to dir when run from a subdirectory
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+
 	// The main repo path is the parent of the .git directory
 	mainPath := filepath.Dir(gitDir)
 
@@ -48,9 +49,7 @@ func GetMainRepoPath(dir string) (string, error) {
 	// If not, we might be in the main repo already
 	if !strings.HasSuffix(gitDir, ".git") {
 		// We're likely in a bare repository or the main repo
-		cmd = exec.Command("git", "rev-parse", "--show-toplevel")
-		cmd.Dir = dir
-		output, err = cmd.CombinedOutput()
+		output, err = c.Run(dir, "rev-parse", "--show-toplevel")
 		if err != nil {
 			return "", err
 		}
@@ -60,25 +59,148 @@ func GetMainRepoPath(dir string) (string, error) {
 	return mainPath, nil
 }
 
+// WorktreeGitDir returns the directory inside the main repository's git
+// dir that holds a linked worktree's administrative files, as named by the
+// worktree's .git file. It fails for anything that is not a linked worktree.
+func WorktreeGitDir(worktreePath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(worktreePath, ".git")) // #nosec G304
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s is not a linked worktree", worktreePath)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(worktreePath, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// MainRepoPathFromWorktree returns the main repository a linked worktree
+// belongs to by reading the worktree's .git file, so unlike GetMainRepoPath
+// it works when git cannot be run from the current directory. It fails for
+// anything that is not a linked worktree.
+func MainRepoPathFromWorktree(worktreePath string) (string, error) {
+	gitDir, err := WorktreeGitDir(worktreePath)
+	if err != nil {
+		return "", err
+	}
+
+	// The worktree's git dir is <common dir>/worktrees/<name>, and says so
+	// in its commondir file
+	commonDir := filepath.Join(gitDir, "..", "..")
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil { // #nosec G304
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	commonDir = filepath.Clean(commonDir)
+
+	if filepath.Base(commonDir) == ".git" {
+		return filepath.Dir(commonDir), nil
+	}
+	// Worktrees of a bare repository
+	return commonDir, nil
+}
+
+// GetCommonDir returns the absolute, symlink-free path of the repository's
+// common git directory, which is shared by the main repository and all of
+// its worktrees
+func (c Client) GetCommonDir(dir string) (string, error) {
+	output, err := c.Run(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	commonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(dir, commonDir)
+	}
+	if resolved, err := filepath.EvalSymlinks(commonDir); err == nil {
+		commonDir = resolved
+	}
+	return filepath.Clean(commonDir), nil
+}
+
+// RepoIDKey is the git config key a repository's ID is recorded under
+const RepoIDKey = "ccswitch.id"
+
+// GetRepoID returns an identifier for the repository that, unlike its
+// directory name, is unique per clone: ~/work/api and ~/oss/api get
+// different IDs. Once recorded with RecordRepoID it is read from the
+// repository's config, so it survives moving or renaming the repository.
+// Until then it is the repository name followed by a short hash of the
+// common git directory. The origin URL is deliberately not used, since two
+// clones of the same remote must not share storage.
+func (c Client) GetRepoID(dir string) (string, error) {
+	if id := c.recordedRepoID(dir); id != "" {
+		return id, nil
+	}
+
+	commonDir, err := c.GetCommonDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(commonDir)
+	if name == ".git" {
+		name = filepath.Base(filepath.Dir(commonDir))
+	}
+	name = strings.TrimSuffix(name, ".git")
+
+	sum := sha256.Sum256([]byte(commonDir))
+	return name + "-" + hex.EncodeToString(sum[:])[:8], nil
+}
+
+// RecordRepoID stores the ID GetRepoID returns in the repository's config,
+// unless one is recorded already, so it stays the same wherever the
+// repository goes. The config is shared by all of its worktrees.
+func (c Client) RecordRepoID(dir string) error {
+	if c.recordedRepoID(dir) != "" {
+		return nil
+	}
+	id, err := c.GetRepoID(dir)
+	if err != nil {
+		return err
+	}
+	if _, err := c.Run(dir, "config", "--local", RepoIDKey, id); err != nil {
+		return fmt.Errorf("failed to record repository ID: %w", err)
+	}
+	return nil
+}
+
+// recordedRepoID returns the ID recorded by RecordRepoID, or "" if there
+// is none. IDs name directories, so one that isn't a plain name is ignored.
+func (c Client) recordedRepoID(dir string) string {
+	output, err := c.Run(dir, "config", "--get", RepoIDKey)
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimSpace(string(output))
+	if id == "." || id == ".." || id != filepath.Base(id) {
+		return ""
+	}
+	return id
+}
+
 // IsGitRepository checks if the directory is a git repository
-func IsGitRepository(dir string) bool {
+func (c Client) IsGitRepository(dir string) bool {
 	_, err := os.Stat(filepath.Join(dir, ".git"))
 	if err == nil {
 		return true
 	}
 
 	// Check if we're in a worktree or subdirectory
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = dir
-	err = cmd.Run()
+	_, err = c.Run(dir, "rev-parse", "--git-dir")
 	return err == nil
 }
 
 // GetCurrentBranch returns the current branch name
-func GetCurrentBranch(dir string) (string, error) {
-	cmd := exec.Command("git", "branch", "--show-current")
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
+func (c Client) GetCurrentBranch(dir string) (string, error) {
+	output, err := c.Run(dir, "branch", "--show-current")
 	if err != nil {
 		return "", err
 	}
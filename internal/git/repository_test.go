@@ -20,7 +20,7 @@ func TestGetRepoName(t *testing.T) {
 	}
 
 	// Test GetRepoName
-	name, err := GetRepoName(tempDir)
+	name, err := Default().GetRepoName(tempDir)
 	if err != nil {
 		t.Fatalf("GetRepoName() failed: %v", err)
 	}
@@ -35,7 +35,7 @@ func TestGetRepoNameNonGitDir(t *testing.T) {
 	// Test with a non-git directory
 	tempDir := t.TempDir()
 
-	_, err := GetRepoName(tempDir)
+	_, err := Default().GetRepoName(tempDir)
 	if err == nil {
 		t.Error("GetRepoName() should fail for non-git directory")
 	}
@@ -71,7 +71,7 @@ func TestGetMainRepoPath(t *testing.T) {
 	}
 
 	// Test from main repository
-	mainPath, err := GetMainRepoPath(tempDir)
+	mainPath, err := Default().GetMainRepoPath(tempDir)
 	if err != nil {
 		t.Fatalf("GetMainRepoPath() from main repo failed: %v", err)
 	}
@@ -98,7 +98,7 @@ func TestGetMainRepoPath(t *testing.T) {
 	}
 
 	// Test from worktree
-	mainPathFromWorktree, err := GetMainRepoPath(worktreeDir)
+	mainPathFromWorktree, err := Default().GetMainRepoPath(worktreeDir)
 	if err != nil {
 		t.Fatalf("GetMainRepoPath() from worktree failed: %v", err)
 	}
@@ -139,7 +139,7 @@ func TestGetMainRepoPathHandlesDotGit(t *testing.T) {
 	}
 
 	// Now test that GetMainRepoPath handles this correctly
-	mainPath, err := GetMainRepoPath(tempDir)
+	mainPath, err := Default().GetMainRepoPath(tempDir)
 	if err != nil {
 		t.Fatalf("GetMainRepoPath() failed: %v", err)
 	}
@@ -166,8 +166,179 @@ func TestGetMainRepoPathNonGitDir(t *testing.T) {
 	// Test with a non-git directory
 	tempDir := t.TempDir()
 
-	_, err := GetMainRepoPath(tempDir)
+	_, err := Default().GetMainRepoPath(tempDir)
 	if err == nil {
 		t.Error("GetMainRepoPath() should fail for non-git directory")
 	}
 }
+
+func TestGetRepoID(t *testing.T) {
+	// Two clones with the same directory name must get different IDs
+	first := filepath.Join(t.TempDir(), "api")
+	second := filepath.Join(t.TempDir(), "api")
+
+	for _, dir := range []string{first, second} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		cmd := exec.Command("git", "init")
+		cmd.Dir = dir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Failed to initialize git repository: %v", err)
+		}
+	}
+
+	firstID, err := Default().GetRepoID(first)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+	secondID, err := Default().GetRepoID(second)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+
+	if !strings.HasPrefix(firstID, "api-") {
+		t.Errorf("GetRepoID() = %q, expected it to start with the repository name", firstID)
+	}
+	if firstID == secondID {
+		t.Errorf("GetRepoID() returned %q for two different clones", firstID)
+	}
+
+	// The ID is stable and the same from a subdirectory
+	subDir := filepath.Join(first, "sub")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	again, err := Default().GetRepoID(subDir)
+	if err != nil {
+		t.Fatalf("GetRepoID() from subdirectory failed: %v", err)
+	}
+	if again != firstID {
+		t.Errorf("GetRepoID() from subdirectory = %q, expected %q", again, firstID)
+	}
+}
+
+func TestRecordRepoID(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "api")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Failed to initialize git repository: %v", err)
+	}
+
+	id, err := Default().GetRepoID(repo)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+	if err := Default().RecordRepoID(repo); err != nil {
+		t.Fatalf("RecordRepoID() failed: %v", err)
+	}
+
+	// Moving the repository no longer changes its ID
+	moved := filepath.Join(t.TempDir(), "renamed-api")
+	if err := os.Rename(repo, moved); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Default().GetRepoID(moved)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+	if again != id {
+		t.Errorf("GetRepoID() after moving = %q, expected the recorded %q", again, id)
+	}
+
+	// Anything but a plain name is ignored
+	cmd = exec.Command("git", "config", RepoIDKey, "../elsewhere")
+	cmd.Dir = moved
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("git config failed: %v", err)
+	}
+	if got, _ := Default().GetRepoID(moved); !strings.HasPrefix(got, "renamed-api-") {
+		t.Errorf("GetRepoID() = %q, expected the unusable recorded ID to be ignored", got)
+	}
+}
+
+func TestMainRepoPathFromWorktree(t *testing.T) {
+	mainRepo := filepath.Join(t.TempDir(), "api")
+	worktree := filepath.Join(t.TempDir(), "fix-bug")
+	if err := os.MkdirAll(mainRepo, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"init"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "--allow-empty", "-m", "init"},
+		{"worktree", "add", "-b", "fix-bug", worktree},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = mainRepo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	got, err := MainRepoPathFromWorktree(worktree)
+	if err != nil {
+		t.Fatalf("MainRepoPathFromWorktree() failed: %v", err)
+	}
+	expected, _ := filepath.EvalSymlinks(mainRepo)
+	if resolved, _ := filepath.EvalSymlinks(got); resolved != expected {
+		t.Errorf("MainRepoPathFromWorktree() = %q, expected %q", got, mainRepo)
+	}
+
+	// The main repository itself is not a linked worktree
+	if _, err := MainRepoPathFromWorktree(mainRepo); err == nil {
+		t.Error("MainRepoPathFromWorktree() should fail for the main repository")
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	tempDir := t.TempDir()
+	repo := filepath.Join(tempDir, "repo")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run(repo, "init", "-b", "trunk")
+	run(repo, "-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "--allow-empty", "-m", "Initial commit")
+
+	if got := Default().DefaultBranch(repo, "release"); got != "release" {
+		t.Errorf("DefaultBranch() with a configured branch = %q, expected release", got)
+	}
+	if got := Default().DefaultBranch(repo, ""); got != "trunk" {
+		t.Errorf("DefaultBranch() = %q, expected the existing trunk", got)
+	}
+
+	// init.defaultBranch only counts when that branch exists
+	run(repo, "config", "init.defaultBranch", "develop")
+	if got := Default().DefaultBranch(repo, ""); got != "trunk" {
+		t.Errorf("DefaultBranch() with a missing init.defaultBranch = %q, expected trunk", got)
+	}
+	run(repo, "branch", "develop")
+	if got := Default().DefaultBranch(repo, ""); got != "develop" {
+		t.Errorf("DefaultBranch() = %q, expected init.defaultBranch develop", got)
+	}
+
+	// origin/HEAD beats everything but configuration
+	clone := filepath.Join(tempDir, "clone")
+	run(tempDir, "clone", "--quiet", repo, clone)
+	run(clone, "branch", "develop")
+	run(clone, "config", "init.defaultBranch", "develop")
+	if got := Default().DefaultBranch(clone, ""); got != "trunk" {
+		t.Errorf("DefaultBranch() of a clone = %q, expected trunk from origin/HEAD", got)
+	}
+}
@@ -0,0 +1,196 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command is one git invocation
+type Command struct {
+	// Dir is the directory git runs in
+	Dir  string
+	Args []string
+	// Env holds KEY=value pairs added to ccswitch's own environment
+	Env []string
+	// Stdin is fed to git when not nil
+	Stdin []byte
+	// Interactive connects git to the terminal, for commands that prompt or
+	// show progress. Nothing is captured, so the error has no Stderr.
+	Interactive bool
+}
+
+// String renders the command the way it could be typed into a shell
+func (c Command) String() string {
+	parts := []string{"git"}
+	if c.Dir != "" {
+		parts = append(parts, "-C", quoteArg(c.Dir))
+	}
+	for _, arg := range c.Args {
+		parts = append(parts, quoteArg(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`*?[]{}()<>|&;#~") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// Runner runs git commands. It returns what the command wrote to stdout;
+// when the command fails, the error is a *CommandError holding its stderr,
+// made with NewCommandError.
+type Runner interface {
+	Run(ctx context.Context, cmd Command) ([]byte, error)
+}
+
+// CommandError is a git command that failed. Its message is what git said
+// went wrong, and errors.Is matches both Err and Kind.
+type CommandError struct {
+	Command  Command
+	ExitCode int
+	Stderr   string
+	// Err is what running the command returned, or the context's error when
+	// the command was cancelled or timed out
+	Err error
+	// Kind is the internal/errors sentinel the failure amounts to, or nil
+	Kind error
+}
+
+// NewCommandError describes a command that failed, recognizing the common
+// failures from its stderr
+func NewCommandError(c Command, exitCode int, stderr string, err error) *CommandError {
+	return &CommandError{Command: c, ExitCode: exitCode, Stderr: stderr, Err: err, Kind: classify(c.Args, stderr, err)}
+}
+
+func (e *CommandError) Error() string {
+	if errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded) {
+		return e.Err.Error()
+	}
+	if message := stderrMessage(e.Stderr); message != "" {
+		return message
+	}
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Kind}
+}
+
+// ExecRunner runs the git binary
+type ExecRunner struct {
+	// Trace, when set, gets every command once it has run, with how long it
+	// took and, if it failed, its exit code
+	Trace io.Writer
+}
+
+// Run implements Runner
+func (r ExecRunner) Run(ctx context.Context, c Command) ([]byte, error) {
+	start := time.Now()
+	stdout, err := r.run(ctx, c)
+	if r.Trace != nil {
+		took := time.Since(start).Round(time.Microsecond)
+		var failed *CommandError
+		if errors.As(err, &failed) {
+			fmt.Fprintf(r.Trace, "+ %s (%s, exit %d)\n", c, took, failed.ExitCode)
+		} else {
+			fmt.Fprintf(r.Trace, "+ %s (%s)\n", c, took)
+		}
+	}
+	return stdout, err
+}
+
+func (r ExecRunner) run(ctx context.Context, c Command) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", c.Args...) // #nosec G204
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	if c.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(c.Stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if c.Interactive {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return stdout.Bytes(), NewCommandError(c, exitCode, stderr.String(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Client runs git commands through a Runner, under a context that can time
+// them out or cancel them
+type Client struct {
+	ctx    context.Context
+	runner Runner
+}
+
+// NewClient creates a Client. A nil runner runs the git binary.
+func NewClient(ctx context.Context, runner Runner) Client {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	return Client{ctx: ctx, runner: runner}
+}
+
+// Default returns a Client that runs the git binary and is never cancelled
+func Default() Client {
+	return NewClient(context.Background(), nil)
+}
+
+// Context returns the context the client's commands run under
+func (c Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithContext returns a copy of the client whose commands run under ctx
+func (c Client) WithContext(ctx context.Context) Client {
+	return NewClient(ctx, c.runner)
+}
+
+// Runner returns the runner the client's commands go through
+func (c Client) Runner() Runner {
+	if c.runner == nil {
+		return ExecRunner{}
+	}
+	return c.runner
+}
+
+// Run runs git with args in dir, returning its stdout
+func (c Client) Run(dir string, args ...string) ([]byte, error) {
+	return c.RunCommand(Command{Dir: dir, Args: args})
+}
+
+// RunCommand runs cmd, returning its stdout
+func (c Client) RunCommand(cmd Command) ([]byte, error) {
+	return c.Runner().Run(c.Context(), cmd)
+}
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExecRunner(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	var trace bytes.Buffer
+	client := NewClient(context.Background(), ExecRunner{Trace: &trace})
+	dir := t.TempDir()
+
+	output, err := client.Run(dir, "init", "--quiet", "-b", "main")
+	if err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	if len(output) != 0 {
+		t.Errorf("git init --quiet wrote %q to stdout", output)
+	}
+	if want := "+ git -C " + dir + " init --quiet -b main ("; !strings.HasPrefix(trace.String(), want) {
+		t.Errorf("trace = %q, expected it to start with %q and the time taken", trace.String(), want)
+	}
+
+	// Failures keep stderr apart from stdout, with the exit code
+	_, err = client.Run(dir, "rev-parse", "--verify", "refs/heads/missing")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("error = %v, expected a *CommandError", err)
+	}
+	if cmdErr.ExitCode != 128 || !strings.Contains(cmdErr.Stderr, "Needed a single revision") {
+		t.Errorf("CommandError = exit %d, stderr %q", cmdErr.ExitCode, cmdErr.Stderr)
+	}
+	if !strings.Contains(trace.String(), ", exit 128)\n") {
+		t.Errorf("trace = %q, expected the failure's exit code", trace.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.WithContext(ctx).Run(dir, "status"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error with a cancelled context = %v, expected context.Canceled", err)
+	}
+}
+
+func TestFakeRunner(t *testing.T) {
+	fake := NewFakeRunner().
+		On("rev-parse --verify refs/heads/main", "abc123\n").
+		Fail("branch -d topic", 1, "error: the branch 'topic' is not fully merged")
+	bm := NewBranchManager(NewClient(context.Background(), fake), "/repo")
+
+	if !bm.Exists("main") {
+		t.Error("Exists(main) = false, expected the fake's answer")
+	}
+	if bm.Exists("other") {
+		t.Error("Exists(other) = true for a command without a response")
+	}
+	err := bm.Delete("topic", false)
+	if err == nil || !strings.Contains(err.Error(), "not fully merged") {
+		t.Errorf("Delete() error = %v, expected the fake's stderr", err)
+	}
+
+	if !fake.Ran("branch -d topic") || len(fake.Calls()) != 3 {
+		t.Errorf("Calls() = %v, expected the three commands", fake.Calls())
+	}
+	if dir := fake.Calls()[0].Dir; dir != "/repo" {
+		t.Errorf("command ran in %q, expected /repo", dir)
+	}
+
+	// Responses for the same command play in turn, the last one repeating
+	fake.Fail("rev-parse --verify refs/heads/topic", 128, "fatal: needed a single revision").
+		On("rev-parse --verify refs/heads/topic", "def456\n")
+	for i, want := range []bool{false, true, true} {
+		if got := bm.Exists("topic"); got != want {
+			t.Errorf("Exists(topic) call %d = %v, expected %v", i+1, got, want)
+		}
+	}
+}
+
+func TestDryRunner(t *testing.T) {
+	fake := NewFakeRunner().On("branch --show-current", "main\n")
+	var out bytes.Buffer
+	client := NewClient(context.Background(), fake).DryRun(&out)
+
+	if branch, err := client.GetCurrentBranch("/repo"); err != nil || branch != "main" {
+		t.Errorf("GetCurrentBranch() = %q, %v, expected read-only commands to run", branch, err)
+	}
+	if err := NewWorktreeManager(client, "/repo").Create("/wt/fix", "fix"); err != nil {
+		t.Errorf("Create() error = %v, expected a dry run to succeed", err)
+	}
+	if fake.Ran("worktree add /wt/fix fix") {
+		t.Error("worktree add ran during a dry run")
+	}
+	if want := "[dry-run] git -C /repo worktree add /wt/fix fix\n"; out.String() != want {
+		t.Errorf("dry run printed %q, expected %q", out.String(), want)
+	}
+
+	if !client.IsDryRun() || client.DryRun(&out).Runner() != client.Runner() {
+		t.Error("DryRun() should wrap a client only once")
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		args string
+		want bool
+	}{
+		{"status --porcelain", true},
+		{"rev-parse --verify main", true},
+		{"worktree list --porcelain", true},
+		{"branch --show-current", true},
+		{"config --get init.defaultBranch", true},
+		{"remote", true},
+		{"symbolic-ref --quiet --short refs/remotes/origin/HEAD", true},
+		{"worktree add /wt/fix fix", false},
+		{"branch --no-track fix main", false},
+		{"branch -D fix", false},
+		{"config branch.fix.remote origin", false},
+		{"update-ref refs/ccswitch/trash/x abc", false},
+		{"symbolic-ref HEAD refs/heads/main", false},
+		{"fetch --prune --quiet origin", false},
+		{"rebase main", false},
+		{"apply --index", false},
+		{"frobnicate", false},
+	}
+	for _, tt := range tests {
+		if got := IsReadOnly(strings.Fields(tt.args)); got != tt.want {
+			t.Errorf("IsReadOnly(%s) = %v, expected %v", tt.args, got, tt.want)
+		}
+	}
+}
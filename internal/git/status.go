@@ -0,0 +1,196 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GetStatus reads the git status of the worktree at path. base is what
+// ahead/behind are counted against when the branch has no upstream; with
+// neither, both stay zero.
+func (c Client) GetStatus(path, base string) (*SessionStatus, error) {
+	output, err := c.Run(path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %w", path, err)
+	}
+	status := ParseStatus(string(output))
+
+	if status.Compare == "" && base != "" {
+		if ahead, behind, err := c.AheadBehind(path, base); err == nil {
+			status.Ahead, status.Behind, status.Compare = ahead, behind, base
+		}
+	}
+
+	// A branch without commits has no last commit; that is not an error
+	if output, err := c.Run(path, "log", "-1", "--format=%ct%x00%s"); err == nil {
+		if ts, subject, ok := strings.Cut(strings.TrimRight(string(output), "\n"), "\x00"); ok {
+			if secs, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				status.LastCommitTime = time.Unix(secs, 0)
+			}
+			status.LastCommitSubject = subject
+		}
+	}
+
+	return status, nil
+}
+
+// AheadBehind counts the commits HEAD of the worktree at path has that ref
+// lacks, and the other way around
+func (c Client) AheadBehind(path, ref string) (ahead, behind int, err error) {
+	output, err := c.Run(path, "rev-list", "--left-right", "--count", "HEAD..."+ref)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare with %s: %w", ref, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", string(output))
+	}
+	ahead, _ = strconv.Atoi(fields[0])
+	behind, _ = strconv.Atoi(fields[1])
+	return ahead, behind, nil
+}
+
+// SquashMerged reports whether the changes HEAD of the worktree at path
+// made since it forked from ref have landed on ref as a single commit, the
+// way a squash merge lands them. Ancestry can't tell; instead the branch is
+// squashed into a throwaway commit and git cherry compares its patch ID
+// with the commits on ref.
+func (c Client) SquashMerged(path, ref string) (bool, error) {
+	git := func(args ...string) (string, error) {
+		output, err := c.RunCommand(Command{
+			Dir:  path,
+			Args: args,
+			// commit-tree wants an identity, which need not be configured
+			Env: []string{
+				"GIT_AUTHOR_NAME=ccswitch", "GIT_AUTHOR_EMAIL=ccswitch@localhost",
+				"GIT_COMMITTER_NAME=ccswitch", "GIT_COMMITTER_EMAIL=ccswitch@localhost",
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	mergeBase, err := git("merge-base", "HEAD", ref)
+	if err != nil {
+		return false, err
+	}
+	tree, err := git("rev-parse", "HEAD^{tree}")
+	if err != nil {
+		return false, err
+	}
+	if baseTree, err := git("rev-parse", mergeBase+"^{tree}"); err != nil || baseTree == tree {
+		// Nothing changed, so nothing was merged
+		return false, err
+	}
+
+	squashed, err := git("commit-tree", tree, "-p", mergeBase, "-m", "ccswitch squash check")
+	if err != nil {
+		return false, err
+	}
+	cherry, err := git("cherry", ref, squashed)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(cherry, "-"), nil
+}
+
+// UncommittedFiles lists the changed files, staged or not, and the
+// untracked files of the worktree at path. Changed files keep the status
+// letters git status --short shows them with.
+func (c Client) UncommittedFiles(path string) (changed, untracked []string, err error) {
+	output, err := c.Run(path, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get status of %s: %w", path, err)
+	}
+	for _, line := range splitLines(string(output)) {
+		if file, ok := strings.CutPrefix(line, "?? "); ok {
+			untracked = append(untracked, file)
+		} else {
+			changed = append(changed, line)
+		}
+	}
+	return changed, untracked, nil
+}
+
+// splitLines splits command output into its non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ParseStatus parses git status --porcelain=v2 --branch output
+func ParseStatus(output string) *SessionStatus {
+	status := &SessionStatus{}
+	hasAB := false
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				status.Head = oid
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			// Only present when the upstream still exists
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+				hasAB = true
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			status.Changed++
+		case strings.HasPrefix(line, "? "):
+			status.Untracked++
+		}
+	}
+
+	if hasAB {
+		status.Compare = status.Upstream
+	} else if status.Upstream != "" {
+		status.UpstreamGone = true
+	}
+
+	return status
+}
+
+// LoadStatuses fills in the Status of every session, reading up to
+// concurrency worktrees at once. Each session is compared against its own
+// base branch, or defaultBase if it has none. Sessions whose status cannot
+// be read keep a nil Status.
+func (c Client) LoadStatuses(sessions []SessionInfo, defaultBase string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range sessions {
+		wg.Add(1)
+		go func(s *SessionInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			base := s.BaseBranch
+			if base == "" {
+				base = defaultBase
+			}
+			if status, err := c.GetStatus(s.Path, base); err == nil {
+				s.Status = status
+			}
+		}(&sessions[i])
+	}
+	wg.Wait()
+}
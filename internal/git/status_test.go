@@ -0,0 +1,154 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	output := `# branch.oid 1234567890abcdef
+# branch.head feature/login
+# branch.upstream origin/feature/login
+# branch.ab +2 -1
+1 .M N... 100644 100644 100644 abc abc src/app.go
+1 M. N... 100644 100644 100644 abc def README.md
+2 R. N... 100644 100644 100644 abc abc R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 abc def ghi conflict.go
+? notes.txt
+? tmp/
+`
+	status := ParseStatus(output)
+
+	if status.Changed != 4 {
+		t.Errorf("Changed = %d, expected 4", status.Changed)
+	}
+	if status.Untracked != 2 {
+		t.Errorf("Untracked = %d, expected 2", status.Untracked)
+	}
+	if status.Ahead != 2 || status.Behind != 1 {
+		t.Errorf("Ahead/Behind = %d/%d, expected 2/1", status.Ahead, status.Behind)
+	}
+	if status.Compare != "origin/feature/login" || status.UpstreamGone {
+		t.Errorf("Compare = %q, expected origin/feature/login", status.Compare)
+	}
+	if status.Head != "1234567890abcdef" {
+		t.Errorf("Head = %q, expected 1234567890abcdef", status.Head)
+	}
+
+	// An upstream that no longer exists has no ahead/behind line
+	status = ParseStatus("# branch.head feature/gone\n# branch.upstream origin/feature/gone\n")
+	if status.Compare != "" || status.Dirty() || !status.UpstreamGone {
+		t.Errorf("ParseStatus() = %+v, expected a clean status with its upstream gone", status)
+	}
+}
+
+func TestLoadStatuses(t *testing.T) {
+	tempDir := t.TempDir()
+	loginPath := filepath.Join(t.TempDir(), "login")
+
+	// main has one commit the session lacks; the session has two of its own
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"commit", "--allow-empty", "-m", "initial commit"},
+		{"branch", "feature/login"},
+		{"commit", "--allow-empty", "-m", "main moves on"},
+		{"worktree", "add", loginPath, "feature/login"},
+		{"-C", loginPath, "commit", "--allow-empty", "-m", "first"},
+		{"-C", loginPath, "commit", "--allow-empty", "-m", "Add login form"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempDir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Failed to run git %v: %v", args, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(loginPath, "scratch.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	sessions := []SessionInfo{
+		{Name: "main", Branch: "main", Path: tempDir},
+		{Name: "login", Branch: "feature/login", Path: loginPath},
+		{Name: "gone", Branch: "feature/gone", Path: filepath.Join(tempDir, "missing")},
+	}
+	Default().LoadStatuses(sessions, "main", 2)
+
+	login := sessions[1].Status
+	if login == nil {
+		t.Fatal("LoadStatuses() did not load the session's status")
+	}
+	if login.Ahead != 2 || login.Behind != 1 || login.Compare != "main" {
+		t.Errorf("Ahead/Behind = %d/%d against %q, expected 2/1 against main", login.Ahead, login.Behind, login.Compare)
+	}
+	if login.Untracked != 1 || !login.Dirty() {
+		t.Errorf("Untracked = %d, expected 1", login.Untracked)
+	}
+	if login.LastCommitSubject != "Add login form" || login.LastCommitTime.IsZero() {
+		t.Errorf("last commit = %q at %v", login.LastCommitSubject, login.LastCommitTime)
+	}
+
+	if sessions[0].Status == nil || sessions[0].Status.Dirty() {
+		t.Errorf("main status = %+v, expected clean", sessions[0].Status)
+	}
+	if sessions[2].Status != nil {
+		t.Errorf("status of a missing worktree = %+v, expected nil", sessions[2].Status)
+	}
+}
+
+func TestSquashMerged(t *testing.T) {
+	tempDir := t.TempDir()
+	squashedPath := filepath.Join(t.TempDir(), "squashed")
+	openPath := filepath.Join(t.TempDir(), "open")
+
+	steps := [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"commit", "--allow-empty", "-m", "initial commit"},
+		{"worktree", "add", "-b", "feature/squashed", squashedPath},
+		{"worktree", "add", "-b", "feature/open", openPath},
+	}
+	for _, args := range steps {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempDir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Failed to run git %v: %v", args, err)
+		}
+	}
+
+	// Two commits on each branch; only feature/squashed is squash-merged
+	commit := func(dir, file, content string) {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		for _, args := range [][]string{{"add", file}, {"commit", "-m", "change " + file}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, output)
+			}
+		}
+	}
+	commit(squashedPath, "a.txt", "one")
+	commit(squashedPath, "a.txt", "two")
+	commit(openPath, "b.txt", "one")
+	commit(openPath, "b.txt", "two")
+	for _, args := range [][]string{{"merge", "--squash", "feature/squashed"}, {"commit", "-m", "Squashed"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	if merged, err := Default().SquashMerged(squashedPath, "main"); err != nil || !merged {
+		t.Errorf("SquashMerged(squashed) = %v, %v; expected true", merged, err)
+	}
+	if merged, err := Default().SquashMerged(openPath, "main"); err != nil || merged {
+		t.Errorf("SquashMerged(open) = %v, %v; expected false", merged, err)
+	}
+}
@@ -1,15 +1,60 @@
 package git
 
+import "time"
+
 // Worktree represents a git worktree
 type Worktree struct {
 	Path   string
 	Branch string
 	Commit string
+	// Prunable is set when git reports the worktree's directory is gone
+	Prunable bool
 }
 
-// SessionInfo represents information about a ccswitch session
+// SessionInfo represents information about a ccswitch session. Its json and
+// yaml forms are part of the --output schema; rename fields with care.
 type SessionInfo struct {
-	Name   string
-	Branch string
-	Path   string
+	Name   string `json:"name" yaml:"name"`
+	Branch string `json:"branch" yaml:"branch"`
+	Path   string `json:"path" yaml:"path"`
+
+	// Metadata recorded by ccswitch; empty for sessions it did not create
+	Description    string    `json:"description" yaml:"description"`
+	BaseBranch     string    `json:"base_branch" yaml:"base_branch"`
+	BaseCommit     string    `json:"base_commit" yaml:"base_commit"`
+	CreatedAt      time.Time `json:"created_at" yaml:"created_at"`
+	LastSwitchedAt time.Time `json:"last_switched_at" yaml:"last_switched_at"`
+
+	// Status is nil until loaded with LoadStatuses
+	Status *SessionStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// SessionStatus is a snapshot of the git state of a session's worktree
+type SessionStatus struct {
+	// Head is the commit checked out in the worktree
+	Head string `json:"head" yaml:"head"`
+
+	// Changed counts modified, staged and conflicted files
+	Changed   int `json:"changed" yaml:"changed"`
+	Untracked int `json:"untracked" yaml:"untracked"`
+
+	// Ahead and Behind count commits relative to Compare: the branch's
+	// upstream if it has one, otherwise the session's base branch
+	Ahead   int    `json:"ahead" yaml:"ahead"`
+	Behind  int    `json:"behind" yaml:"behind"`
+	Compare string `json:"compare" yaml:"compare"`
+
+	// Upstream is the configured upstream branch, if any. UpstreamGone is
+	// set when it no longer exists, typically after the remote branch was
+	// deleted on merge.
+	Upstream     string `json:"upstream" yaml:"upstream"`
+	UpstreamGone bool   `json:"upstream_gone" yaml:"upstream_gone"`
+
+	LastCommitSubject string    `json:"last_commit_subject" yaml:"last_commit_subject"`
+	LastCommitTime    time.Time `json:"last_commit_time" yaml:"last_commit_time"`
+}
+
+// Dirty reports whether the worktree has uncommitted or untracked files
+func (s *SessionStatus) Dirty() bool {
+	return s.Changed > 0 || s.Untracked > 0
 }
@@ -2,7 +2,6 @@ package git
 
 import (
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -10,30 +9,32 @@ import (
 
 // WorktreeManager handles git worktree operations
 type WorktreeManager struct {
+	git      Client
 	repoPath string
 }
 
-// NewWorktreeManager creates a new WorktreeManager
-func NewWorktreeManager(repoPath string) *WorktreeManager {
-	return &WorktreeManager{repoPath: repoPath}
+// NewWorktreeManager creates a new WorktreeManager that runs git through
+// client
+func NewWorktreeManager(client Client, repoPath string) *WorktreeManager {
+	return &WorktreeManager{git: client, repoPath: repoPath}
+}
+
+// RepoPath returns the repository the manager operates on
+func (wm *WorktreeManager) RepoPath() string {
+	return wm.repoPath
 }
 
 // Create creates a new worktree
 func (wm *WorktreeManager) Create(path, branch string) error {
-	cmd := exec.Command("git", "worktree", "add", path, branch)
-	cmd.Dir = wm.repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create worktree: %w, output: %s", err, string(output))
+	if _, err := wm.git.Run(wm.repoPath, "worktree", "add", path, branch); err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 	return nil
 }
 
 // List returns all worktrees
 func (wm *WorktreeManager) List() ([]Worktree, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = wm.repoPath
-	output, err := cmd.CombinedOutput()
+	output, err := wm.git.Run(wm.repoPath, "worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
@@ -41,11 +42,41 @@ func (wm *WorktreeManager) List() ([]Worktree, error) {
 }
 
 // Remove removes a worktree
-func (wm *WorktreeManager) Remove(path string) error {
-	cmd := exec.Command("git", "worktree", "remove", path, "--force")
-	cmd.Dir = wm.repoPath
-	_, err := cmd.CombinedOutput()
-	return err
+func (wm *WorktreeManager) Remove(path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := wm.git.Run(wm.repoPath, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Move moves a worktree to a new location
+func (wm *WorktreeManager) Move(oldPath, newPath string) error {
+	if _, err := wm.git.Run(wm.repoPath, "worktree", "move", oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to move worktree: %w", err)
+	}
+	return nil
+}
+
+// Prune drops the registration of worktrees whose directories are gone
+func (wm *WorktreeManager) Prune() error {
+	if _, err := wm.git.Run(wm.repoPath, "worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// Repair fixes the links between the repository and its worktrees after
+// either was moved by hand. Paths name worktrees moved elsewhere; without
+// any, only worktrees git still knows the location of are repaired.
+func (wm *WorktreeManager) Repair(paths ...string) error {
+	if _, err := wm.git.Run(wm.repoPath, append([]string{"worktree", "repair"}, paths...)...); err != nil {
+		return fmt.Errorf("failed to repair worktrees: %w", err)
+	}
+	return nil
 }
 
 // ParseWorktrees parses git worktree list --porcelain output
@@ -72,6 +103,8 @@ func ParseWorktrees(output string) []Worktree {
 			currentWorktree.Branch = matches[1]
 		} else if strings.HasPrefix(line, "HEAD ") {
 			currentWorktree.Commit = strings.TrimPrefix(line, "HEAD ")
+		} else if line == "prunable" || strings.HasPrefix(line, "prunable ") {
+			currentWorktree.Prunable = true
 		}
 	}
 
@@ -82,14 +115,33 @@ func ParseWorktrees(output string) []Worktree {
 	return worktrees
 }
 
-// GetSessionsFromWorktrees extracts session information from worktrees
-func GetSessionsFromWorktrees(worktrees []Worktree, repoName string) []SessionInfo {
+// SessionMatcher reports whether a worktree path belongs to a ccswitch
+// session, and if so, which one
+type SessionMatcher func(path string) (name string, ok bool)
+
+// GetSessionsFromWorktrees extracts session information from worktrees.
+// Sessions are recognized by the given matchers; without any, the
+// centralized ~/.ccswitch/worktrees/<repoName>/ layout is assumed.
+func GetSessionsFromWorktrees(worktrees []Worktree, repoName string, matchers ...SessionMatcher) []SessionInfo {
+	if len(matchers) == 0 {
+		matchers = []SessionMatcher{centralizedMatcher(repoName)}
+	}
+
+	match := func(path string) (string, bool) {
+		for _, m := range matchers {
+			if name, ok := m(path); ok {
+				return name, true
+			}
+		}
+		return "", false
+	}
+
 	var sessions []SessionInfo
 
 	// First, find and add the main repository
 	for _, wt := range worktrees {
-		// Check if this is the main worktree (not in .ccswitch directory)
-		if !strings.Contains(wt.Path, ".ccswitch") && wt.Branch != "" {
+		// Check if this is the main worktree (not a ccswitch session)
+		if _, ok := match(wt.Path); !ok && !strings.Contains(wt.Path, ".ccswitch") && wt.Branch != "" {
 			// This is likely the main repository
 			sessions = append(sessions, SessionInfo{
 				Name:   "main",
@@ -101,27 +153,38 @@ func GetSessionsFromWorktrees(worktrees []Worktree, repoName string) []SessionIn
 	}
 
 	// Then add all ccswitch worktrees for this specific repo
-	// The pattern should match worktrees that belong to this repository
 	for _, wt := range worktrees {
-		// Check if it's a ccswitch worktree and extract the repo name from path
-		if strings.Contains(wt.Path, ".ccswitch/worktrees/") && wt.Branch != "" {
-			// Extract repo name from path to ensure we only show worktrees for current repo
-			parts := strings.Split(wt.Path, string(filepath.Separator))
-			for i, part := range parts {
-				if part == ".ccswitch" && i+2 < len(parts) && parts[i+1] == "worktrees" {
-					if i+2 < len(parts) && parts[i+2] == repoName {
-						sessionName := filepath.Base(wt.Path)
-						sessions = append(sessions, SessionInfo{
-							Name:   sessionName,
-							Branch: wt.Branch,
-							Path:   wt.Path,
-						})
-					}
-					break
-				}
-			}
+		if wt.Branch == "" {
+			continue
+		}
+		if name, ok := match(wt.Path); ok {
+			sessions = append(sessions, SessionInfo{
+				Name:   name,
+				Branch: wt.Branch,
+				Path:   wt.Path,
+			})
 		}
 	}
 
 	return sessions
 }
+
+// centralizedMatcher recognizes sessions stored under .ccswitch/worktrees/<repoName>/
+func centralizedMatcher(repoName string) SessionMatcher {
+	return func(path string) (string, bool) {
+		if !strings.Contains(path, ".ccswitch/worktrees/") {
+			return "", false
+		}
+		// Extract repo name from path to ensure we only show worktrees for current repo
+		parts := strings.Split(path, string(filepath.Separator))
+		for i, part := range parts {
+			if part == ".ccswitch" && i+2 < len(parts) && parts[i+1] == "worktrees" {
+				if parts[i+2] == repoName {
+					return filepath.Base(path), true
+				}
+				break
+			}
+		}
+		return "", false
+	}
+}
@@ -54,6 +54,22 @@ detached
 				{Path: "/home/user/project", Branch: "", Commit: "abc123def"},
 			},
 		},
+		{
+			name: "prunable worktree",
+			input: `worktree /home/user/project
+HEAD abc123
+branch refs/heads/main
+
+worktree /home/user/.ccswitch/worktrees/project/gone
+HEAD def456
+branch refs/heads/feature/gone
+prunable gitdir file points to non-existent location
+`,
+			expected: []Worktree{
+				{Path: "/home/user/project", Branch: "main", Commit: "abc123"},
+				{Path: "/home/user/.ccswitch/worktrees/project/gone", Branch: "feature/gone", Commit: "def456", Prunable: true},
+			},
+		},
 		{
 			name:     "empty input",
 			input:    "",
@@ -130,7 +146,7 @@ func TestWorktreeManagerCreate(t *testing.T) {
 	}
 
 	// Create WorktreeManager
-	wm := NewWorktreeManager(tempDir)
+	wm := NewWorktreeManager(Default(), tempDir)
 
 	// Test creating a worktree
 	worktreePath := filepath.Join(tempDir, "test-worktree")
@@ -214,7 +230,7 @@ func TestWorktreeManagerCreateWithMainRepoPath(t *testing.T) {
 	}
 
 	// Get the main repo path (should handle the ".git" case correctly)
-	mainRepoPath, err := GetMainRepoPath(tempDir)
+	mainRepoPath, err := Default().GetMainRepoPath(tempDir)
 	if err != nil {
 		t.Fatalf("GetMainRepoPath() failed: %v", err)
 	}
@@ -228,7 +244,7 @@ func TestWorktreeManagerCreateWithMainRepoPath(t *testing.T) {
 	}
 
 	// Create WorktreeManager with the main repo path
-	wm := NewWorktreeManager(mainRepoPath)
+	wm := NewWorktreeManager(Default(), mainRepoPath)
 
 	// Simulate the path that would be used in real usage
 	repoName := filepath.Base(mainRepoPath)
@@ -379,3 +395,97 @@ func TestGetSessionsFromWorktrees(t *testing.T) {
 		})
 	}
 }
+
+func TestWorktreeManagerMoveWithBranchRename(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Initialize a git repository with an initial commit
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"commit", "--allow-empty", "-m", "initial commit"},
+		{"branch", "feature/old-name"},
+		{"config", "branch.feature/old-name.remote", "origin"},
+		{"config", "branch.feature/old-name.merge", "refs/heads/feature/old-name"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempDir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Failed to run git %v: %v", args, err)
+		}
+	}
+
+	wm := NewWorktreeManager(Default(), tempDir)
+	bm := NewBranchManager(Default(), tempDir)
+
+	oldPath := filepath.Join(tempDir, "sessions", "old-name")
+	newPath := filepath.Join(tempDir, "sessions", "new-name")
+	if err := wm.Create(oldPath, "feature/old-name"); err != nil {
+		t.Fatalf("WorktreeManager.Create() failed: %v", err)
+	}
+
+	if err := bm.Rename("feature/old-name", "feature/new-name"); err != nil {
+		t.Fatalf("BranchManager.Rename() failed: %v", err)
+	}
+	if err := wm.Move(oldPath, newPath); err != nil {
+		t.Fatalf("WorktreeManager.Move() failed: %v", err)
+	}
+
+	worktrees, err := wm.List()
+	if err != nil {
+		t.Fatalf("WorktreeManager.List() failed: %v", err)
+	}
+
+	found := false
+	for _, wt := range worktrees {
+		if strings.TrimPrefix(wt.Path, "/private") == strings.TrimPrefix(newPath, "/private") {
+			found = true
+			if wt.Branch != "feature/new-name" {
+				t.Errorf("Moved worktree branch = %q, expected %q", wt.Branch, "feature/new-name")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Moved worktree not found at %s", newPath)
+	}
+
+	// Upstream tracking should follow the branch
+	cmd := exec.Command("git", "config", "--get", "branch.feature/new-name.merge")
+	cmd.Dir = tempDir
+	output, err := cmd.Output()
+	if err != nil || strings.TrimSpace(string(output)) != "refs/heads/feature/old-name" {
+		t.Errorf("Upstream tracking was not preserved: %q, %v", string(output), err)
+	}
+}
+
+func TestGetSessionsFromWorktreesWithMatchers(t *testing.T) {
+	sibling := func(path string) (string, bool) {
+		name := filepath.Base(path)
+		if filepath.Dir(path) == "/home/user/code" && strings.HasPrefix(name, "myrepo-") {
+			return strings.TrimPrefix(name, "myrepo-"), true
+		}
+		return "", false
+	}
+
+	worktrees := []Worktree{
+		{Path: "/home/user/code/myrepo", Branch: "main", Commit: "abc123"},
+		{Path: "/home/user/code/myrepo-feature", Branch: "feature/test", Commit: "def456"},
+		{Path: "/home/user/code/other", Branch: "other", Commit: "ghi789"},
+	}
+
+	result := GetSessionsFromWorktrees(worktrees, "myrepo", sibling)
+	expected := []SessionInfo{
+		{Name: "main", Branch: "main", Path: "/home/user/code/myrepo"},
+		{Name: "feature", Branch: "feature/test", Path: "/home/user/code/myrepo-feature"},
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("GetSessionsFromWorktrees() returned %d sessions, expected %d: %+v", len(result), len(expected), result)
+	}
+	for i := range result {
+		if result[i].Name != expected[i].Name || result[i].Path != expected[i].Path {
+			t.Errorf("Session[%d] = %+v, expected %+v", i, result[i], expected[i])
+		}
+	}
+}
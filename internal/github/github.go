@@ -0,0 +1,62 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// Pull request states as reported by gh
+const (
+	StateOpen   = "OPEN"
+	StateClosed = "CLOSED"
+	StateMerged = "MERGED"
+)
+
+// prListLimit is how many recent pull requests are looked at
+const prListLimit = 200
+
+// PullRequest is a pull request as reported by the GitHub CLI
+type PullRequest struct {
+	Number      int    `json:"number"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	HeadRefName string `json:"headRefName"`
+}
+
+// Available reports whether the GitHub CLI is installed
+func Available() bool {
+	_, err := exec.LookPath("gh")
+	return err == nil
+}
+
+// PullRequestsByBranch lists the repository's recent pull requests with a
+// single gh call, keyed by head branch. When a branch has several, an open
+// one wins, then the most recent.
+func PullRequestsByBranch(dir string) (map[string]PullRequest, error) {
+	cmd := exec.Command("gh", "pr", "list", "--state", "all", "--limit", fmt.Sprint(prListLimit),
+		"--json", "number,state,url,headRefName") // #nosec G204
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	return parsePullRequests(output)
+}
+
+func parsePullRequests(data []byte) (map[string]PullRequest, error) {
+	var prs []PullRequest
+	if err := json.Unmarshal(data, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse pull requests: %w", err)
+	}
+
+	// gh lists the most recent first
+	byBranch := make(map[string]PullRequest, len(prs))
+	for _, pr := range prs {
+		existing, seen := byBranch[pr.HeadRefName]
+		if !seen || (pr.State == StateOpen && existing.State != StateOpen) {
+			byBranch[pr.HeadRefName] = pr
+		}
+	}
+	return byBranch, nil
+}
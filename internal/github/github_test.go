@@ -0,0 +1,31 @@
+package github
+
+import "testing"
+
+func TestParsePullRequests(t *testing.T) {
+	data := []byte(`[
+		{"number": 12, "state": "CLOSED", "url": "https://github.com/o/r/pull/12", "headRefName": "feature/login"},
+		{"number": 11, "state": "MERGED", "url": "https://github.com/o/r/pull/11", "headRefName": "feature/signup"},
+		{"number": 10, "state": "OPEN", "url": "https://github.com/o/r/pull/10", "headRefName": "feature/login"},
+		{"number": 9, "state": "CLOSED", "url": "https://github.com/o/r/pull/9", "headRefName": "feature/signup"}
+	]`)
+
+	prs, err := parsePullRequests(data)
+	if err != nil {
+		t.Fatalf("parsePullRequests() failed: %v", err)
+	}
+
+	if pr := prs["feature/login"]; pr.Number != 10 {
+		t.Errorf("feature/login PR = #%d, expected the open #10", pr.Number)
+	}
+	if pr := prs["feature/signup"]; pr.Number != 11 || pr.State != StateMerged {
+		t.Errorf("feature/signup PR = #%d %s, expected the most recent #11", pr.Number, pr.State)
+	}
+	if _, ok := prs["feature/none"]; ok {
+		t.Error("found a PR for a branch without one")
+	}
+
+	if _, err := parsePullRequests([]byte("not json")); err == nil {
+		t.Error("parsePullRequests() should fail on invalid output")
+	}
+}
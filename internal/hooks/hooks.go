@@ -0,0 +1,102 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/ui"
+)
+
+// Event identifies a point in the session lifecycle
+type Event string
+
+// Lifecycle events hooks can be attached to
+const (
+	PreCreate  Event = "pre_create"
+	PostCreate Event = "post_create"
+	PreSwitch  Event = "pre_switch"
+	PostSwitch Event = "post_switch"
+	PreRemove  Event = "pre_remove"
+	PostRemove Event = "post_remove"
+)
+
+// Context describes the session a hook runs for. It is exposed to hook
+// commands as CCSWITCH_* environment variables.
+type Context struct {
+	Session  string
+	Branch   string
+	RepoRoot string
+	Worktree string
+}
+
+// Commands returns the commands configured for an event
+func Commands(h config.Hooks, event Event) []string {
+	switch event {
+	case PreCreate:
+		return h.PreCreate
+	case PostCreate:
+		return h.PostCreate
+	case PreSwitch:
+		return h.PreSwitch
+	case PostSwitch:
+		return h.PostSwitch
+	case PreRemove:
+		return h.PreRemove
+	case PostRemove:
+		return h.PostRemove
+	default:
+		return nil
+	}
+}
+
+// Run runs the commands for an event one after another in dir, streaming
+// their output. It stops at the first command that fails. Everything goes to
+// stderr, keeping stdout free for the shell wrapper.
+func Run(h config.Hooks, event Event, dir string, ctx Context) error {
+	for _, command := range Commands(h, event) {
+		fmt.Fprintln(os.Stderr, ui.InfoStyle.Render(fmt.Sprintf("⚙️  %s: %s", event, command)))
+
+		out := ui.NewStreamWriter("  │ ")
+		cmd := shellCommand(command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), ctx.env(event)...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		err := cmd.Run()
+		out.Flush()
+		if err != nil {
+			return fmt.Errorf("%w: %s hook %q: %v", errors.ErrHookFailed, event, command, err)
+		}
+	}
+	return nil
+}
+
+func (c Context) env(event Event) []string {
+	return []string{
+		"CCSWITCH_HOOK=" + string(event),
+		"CCSWITCH_SESSION=" + c.Session,
+		"CCSWITCH_BRANCH=" + c.Branch,
+		"CCSWITCH_REPO_ROOT=" + c.RepoRoot,
+		"CCSWITCH_WORKTREE=" + c.Worktree,
+	}
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command) // #nosec G204
+	}
+	return exec.Command("sh", "-c", command) // #nosec G204
+}
+
+// RunPost runs the commands for an event that fires after an operation has
+// already succeeded. Failures are reported but don't undo the operation.
+func RunPost(h config.Hooks, event Event, dir string, ctx Context) {
+	if err := Run(h, event, dir, ctx); err != nil {
+		fmt.Fprintln(os.Stderr, ui.WarningStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+	}
+}
@@ -0,0 +1,100 @@
+package hooks
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/ui"
+)
+
+func TestRunExposesContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh syntax")
+	}
+
+	dir := t.TempDir()
+	h := config.Hooks{
+		PostCreate: []string{
+			`printf '%s|%s|%s|%s|%s' "$CCSWITCH_HOOK" "$CCSWITCH_SESSION" "$CCSWITCH_BRANCH" "$CCSWITCH_REPO_ROOT" "$PWD" > hook.out`,
+		},
+	}
+	ctx := Context{Session: "fix-bug", Branch: "feature/fix-bug", RepoRoot: "/repo", Worktree: dir}
+
+	if err := Run(h, PostCreate, dir, ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hook.out"))
+	if err != nil {
+		t.Fatalf("Hook did not run in the expected directory: %v", err)
+	}
+
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 5 {
+		t.Fatalf("Unexpected hook output %q", string(data))
+	}
+	if parts[0] != "post_create" || parts[1] != "fix-bug" || parts[2] != "feature/fix-bug" || parts[3] != "/repo" {
+		t.Errorf("Hook environment = %q", string(data))
+	}
+	if strings.TrimPrefix(parts[4], "/private") != strings.TrimPrefix(dir, "/private") {
+		t.Errorf("Hook ran in %q, expected %q", parts[4], dir)
+	}
+}
+
+func TestRunStopsAtFirstFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh syntax")
+	}
+
+	dir := t.TempDir()
+	h := config.Hooks{
+		PreCreate: []string{"exit 3", "touch should-not-exist"},
+	}
+
+	err := Run(h, PreCreate, dir, Context{})
+	if !errors.IsHookFailed(err) {
+		t.Fatalf("Run() error = %v, expected a hook failure", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "should-not-exist")); statErr == nil {
+		t.Error("Run() kept going after a failing hook")
+	}
+}
+
+func TestRunWithoutCommands(t *testing.T) {
+	if err := Run(config.Hooks{}, PreRemove, t.TempDir(), Context{}); err != nil {
+		t.Errorf("Run() with no hooks failed: %v", err)
+	}
+}
+
+func TestRunLeavesStdoutAlone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh syntax")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	ui.SetOutput(w)
+	defer func() {
+		os.Stdout = stdout
+		ui.SetOutput(stdout)
+	}()
+
+	h := config.Hooks{PostSwitch: []string{"echo cd /somewhere", "exit 1"}}
+	RunPost(h, PostSwitch, t.TempDir(), Context{})
+
+	w.Close()
+	data, _ := io.ReadAll(r)
+	if len(data) != 0 {
+		t.Errorf("Hooks wrote %q to stdout, which the shell wrapper reads", string(data))
+	}
+}
@@ -0,0 +1,184 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/config"
+)
+
+// Built-in layouts
+const (
+	// Centralized keeps every session under ~/.ccswitch/worktrees/<repo-id>/
+	Centralized = "centralized"
+	// Sibling puts sessions next to the repository as <repo>-<session>
+	Sibling = "sibling"
+)
+
+// Templates behind the built-in layouts
+const (
+	CentralizedTemplate = "{root}/{repo_id}/{session}"
+	SiblingTemplate     = "{repo_path}/{relative_path}/{repo}-{session}"
+
+	// LegacyCentralizedTemplate is where centralized sessions lived before
+	// they were namespaced by repository ID
+	LegacyCentralizedTemplate = "{root}/{repo}/{session}"
+)
+
+// Repo identifies the repository a layout is resolved for
+type Repo struct {
+	// Name is the repository's directory name
+	Name string
+	// ID is unique per clone, see git.GetRepoID
+	ID string
+	// Path is the main repository's working directory
+	Path string
+}
+
+var placeholderRegex = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Layout resolves where a repository's session worktrees live, and
+// recognizes paths that were created with it
+type Layout struct {
+	template string
+	vars     map[string]string
+	matcher  *regexp.Regexp
+}
+
+// Root returns the directory holding centralized worktrees
+func Root() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "worktrees"), nil
+}
+
+// Stored splits a path directly in the worktree storage,
+// <root>/<dir>/<session>, into the directory, normally a repository ID, and
+// the session. Other paths aren't stored.
+func Stored(path string) (dir, session string, ok bool) {
+	root, err := Root()
+	if err != nil {
+		return "", "", false
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 2 || parts[0] == ".." || parts[0] == "." {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// New creates the layout configured in cfg for a repository. Layout may be
+// "centralized", "sibling" or a template using the placeholders {home},
+// {root}, {repo}, {repo_id}, {repo_path}, {relative_path} and {session}.
+func New(cfg *config.Config, repo Repo) (*Layout, error) {
+	template := cfg.Worktree.Layout
+	switch template {
+	case "", Centralized:
+		template = CentralizedTemplate
+	case Sibling:
+		template = SiblingTemplate
+	}
+	return FromTemplate(template, repo, cfg.Worktree.RelativePath)
+}
+
+// FromTemplate creates a layout from a path template
+func FromTemplate(template string, repo Repo, relativePath string) (*Layout, error) {
+	if !strings.Contains(template, "{session}") {
+		return nil, fmt.Errorf("invalid worktree layout %q: must contain {session}", template)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Layout{
+		template: template,
+		vars: map[string]string{
+			"{home}":          homeDir,
+			"{root}":          root,
+			"{repo}":          repo.Name,
+			"{repo_id}":       repo.ID,
+			"{repo_path}":     repo.Path,
+			"{relative_path}": relativePath,
+		},
+	}
+
+	for _, placeholder := range placeholderRegex.FindAllString(template, -1) {
+		if _, ok := l.vars[placeholder]; !ok && placeholder != "{session}" {
+			return nil, fmt.Errorf("invalid worktree layout %q: unknown placeholder %s", template, placeholder)
+		}
+	}
+
+	// Build a pattern matching any path this layout would produce
+	parts := strings.Split(l.expand(template), "{session}")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(filepath.ToSlash(part))
+	}
+	pattern := "^" + strings.Join(parts, `([^/]+)`) + "$"
+	if l.matcher, err = regexp.Compile(pattern); err != nil {
+		return nil, fmt.Errorf("invalid worktree layout %q: %w", template, err)
+	}
+
+	return l, nil
+}
+
+// Template returns the path template behind the layout
+func (l *Layout) Template() string {
+	return l.template
+}
+
+// SessionPath returns where the worktree for a session lives
+func (l *Layout) SessionPath(session string) string {
+	return l.clean(strings.ReplaceAll(l.expand(l.template), "{session}", session))
+}
+
+// SessionName returns the session a worktree path belongs to, if the path
+// was produced by this layout
+func (l *Layout) SessionName(path string) (string, bool) {
+	matches := l.matcher.FindStringSubmatch(filepath.ToSlash(filepath.Clean(path)))
+	if matches == nil {
+		return "", false
+	}
+	// Every {session} in the template must agree
+	for _, m := range matches[2:] {
+		if m != matches[1] {
+			return "", false
+		}
+	}
+	return matches[1], true
+}
+
+// expand substitutes every placeholder except {session} and normalizes the
+// result, keeping {session} intact so it can be matched in reverse
+func (l *Layout) expand(template string) string {
+	expanded := template
+	if strings.HasPrefix(expanded, "~/") {
+		expanded = "{home}" + expanded[1:]
+	}
+	for placeholder, value := range l.vars {
+		expanded = strings.ReplaceAll(expanded, placeholder, filepath.ToSlash(value))
+	}
+	// Relative templates are relative to the repository
+	if !filepath.IsAbs(filepath.FromSlash(expanded)) {
+		expanded = filepath.ToSlash(l.vars["{repo_path}"]) + "/" + expanded
+	}
+	return filepath.ToSlash(l.clean(expanded))
+}
+
+func (l *Layout) clean(path string) string {
+	return filepath.Clean(filepath.FromSlash(path))
+}
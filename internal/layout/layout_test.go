@@ -0,0 +1,107 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/config"
+)
+
+func TestLayouts(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repoPath := filepath.Join(tempDir, "code", "api")
+	repo := Repo{Name: "api", ID: "api-1a2b3c4d", Path: repoPath}
+
+	tests := []struct {
+		name     string
+		layout   string
+		expected string
+	}{
+		{"default is centralized", "", filepath.Join(tempDir, ".ccswitch", "worktrees", "api-1a2b3c4d", "fix-bug")},
+		{"centralized", Centralized, filepath.Join(tempDir, ".ccswitch", "worktrees", "api-1a2b3c4d", "fix-bug")},
+		{"sibling", Sibling, filepath.Join(tempDir, "code", "api-fix-bug")},
+		{"custom absolute template", "{home}/wt/{repo}/{session}", filepath.Join(tempDir, "wt", "api", "fix-bug")},
+		{"custom tilde template", "~/sessions/{repo}.{session}", filepath.Join(tempDir, "sessions", "api.fix-bug")},
+		{"custom relative template", ".worktrees/{session}", filepath.Join(repoPath, ".worktrees", "fix-bug")},
+		{"legacy centralized", LegacyCentralizedTemplate, filepath.Join(tempDir, ".ccswitch", "worktrees", "api", "fix-bug")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Worktree.Layout = tt.layout
+
+			l, err := New(cfg, repo)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			path := l.SessionPath("fix-bug")
+			if path != tt.expected {
+				t.Errorf("SessionPath() = %q, expected %q", path, tt.expected)
+			}
+
+			name, ok := l.SessionName(path)
+			if !ok || name != "fix-bug" {
+				t.Errorf("SessionName(%q) = %q, %v; expected fix-bug, true", path, name, ok)
+			}
+
+			if _, ok := l.SessionName(repoPath); ok {
+				t.Errorf("SessionName() matched the main repository")
+			}
+		})
+	}
+}
+
+func TestSessionNameRejectsOtherRepos(t *testing.T) {
+	l, err := FromTemplate(CentralizedTemplate, Repo{Name: "api", ID: "api-1a2b3c4d", Path: "/code/api"}, "../")
+	if err != nil {
+		t.Fatalf("FromTemplate() failed: %v", err)
+	}
+
+	root, _ := Root()
+	if _, ok := l.SessionName(filepath.Join(root, "web-5e6f7a8b", "fix-bug")); ok {
+		t.Error("SessionName() matched a session from another repository")
+	}
+	if _, ok := l.SessionName(filepath.Join(root, "api-9c0d1e2f", "fix-bug")); ok {
+		t.Error("SessionName() matched a session from another clone with the same name")
+	}
+	if _, ok := l.SessionName(filepath.Join(root, "api-1a2b3c4d", "nested", "fix-bug")); ok {
+		t.Error("SessionName() matched a nested directory")
+	}
+}
+
+func TestStored(t *testing.T) {
+	root, _ := Root()
+	tests := []struct {
+		path    string
+		dir     string
+		session string
+		ok      bool
+	}{
+		{filepath.Join(root, "api-1a2b3c4d", "fix-bug"), "api-1a2b3c4d", "fix-bug", true},
+		{filepath.Join(root, "api", "fix-bug"), "api", "fix-bug", true},
+		{filepath.Join(root, "api-1a2b3c4d"), "", "", false},
+		{filepath.Join(root, "api-1a2b3c4d", "nested", "fix-bug"), "", "", false},
+		{filepath.Join(filepath.Dir(root), "elsewhere", "fix-bug"), "", "", false},
+	}
+	for _, tt := range tests {
+		dir, session, ok := Stored(tt.path)
+		if dir != tt.dir || session != tt.session || ok != tt.ok {
+			t.Errorf("Stored(%q) = %q, %q, %v; expected %q, %q, %v", tt.path, dir, session, ok, tt.dir, tt.session, tt.ok)
+		}
+	}
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	for _, template := range []string{"{root}/{repo}", "{root}/{unknown}/{session}"} {
+		if _, err := FromTemplate(template, Repo{Name: "api", ID: "api-1a2b3c4d", Path: "/code/api"}, "../"); err == nil {
+			t.Errorf("FromTemplate(%q) should fail", template)
+		}
+	}
+}
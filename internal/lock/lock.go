@@ -0,0 +1,103 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/errors"
+)
+
+// DefaultTimeout is how long to wait for another ccswitch to finish
+const DefaultTimeout = 30 * time.Second
+
+// pollInterval is how often a busy lock is retried
+const pollInterval = 50 * time.Millisecond
+
+// Lock is an advisory lock held on a file under ~/.ccswitch/locks. The
+// operating system releases it if the process dies, so a crash never leaves
+// a repository locked.
+type Lock struct {
+	file *os.File
+}
+
+// Dir returns the directory holding lock files
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "locks"), nil
+}
+
+// Acquire takes the named lock, waiting up to timeout for whoever holds it.
+// On timeout the error wraps errors.ErrLocked and names the holder's pid.
+func Acquire(name string, timeout time.Duration) (*Lock, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create lock directory")
+	}
+
+	path := filepath.Join(dir, name+".lock")
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644) // #nosec G304
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock file")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, errors.Wrap(err, "failed to lock "+path)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			pid := holder(path)
+			file.Close()
+			if pid == "" {
+				return nil, errors.ErrLocked
+			}
+			return nil, fmt.Errorf("%w (pid %s)", errors.ErrLocked, pid)
+		}
+		time.Sleep(pollInterval)
+	}
+
+	// Leave our pid behind so anyone waiting can say who they are waiting on
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Release gives up the lock. The lock file is left in place; removing it
+// would race with processes that have already opened it.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlock(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// holder returns the pid recorded in a lock file, if any
+func holder(path string) string {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
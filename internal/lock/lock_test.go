@@ -0,0 +1,55 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/errors"
+)
+
+func TestAcquireIsExclusive(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	first, err := Acquire("repo", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() failed: %v", err)
+	}
+
+	start := time.Now()
+	_, err = Acquire("repo", 200*time.Millisecond)
+	if !errors.IsLocked(err) {
+		t.Fatalf("second Acquire() error = %v, expected ErrLocked", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("error %q should name the holder's pid", err)
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Error("second Acquire() gave up before the timeout")
+	}
+
+	// Other repositories are not affected
+	other, err := Acquire("other-repo", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() of another lock failed: %v", err)
+	}
+	other.Release()
+
+	// Once released, a waiter gets the lock
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Release()
+	}()
+	second, err := Acquire("repo", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() after release failed: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Release() failed: %v", err)
+	}
+}
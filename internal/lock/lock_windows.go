@@ -0,0 +1,29 @@
+//go:build windows
+
+package lock
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows locks are mandatory for the locked byte range, so lock a byte far
+// past the end of the file to keep the recorded pid readable
+var lockRange = windows.Overlapped{OffsetHigh: 1}
+
+// tryLock takes an exclusive lock without blocking, reporting whether it
+// was free
+func tryLock(file *os.File) (bool, error) {
+	ol := lockRange
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(file *os.File) error {
+	ol := lockRange
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &ol)
+}
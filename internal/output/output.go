@@ -0,0 +1,95 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a command prints its result
+type Format string
+
+// Supported formats. Text is the human-friendly default; the others are
+// stable, machine-readable encodings of the same data.
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TSV  Format = "tsv"
+)
+
+// ParseFormat validates a --output value
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Text, JSON, YAML, TSV:
+		return f, nil
+	case "":
+		return Text, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q, expected text, json, yaml or tsv", s)
+	}
+}
+
+// Machine reports whether the format is meant for programs rather than people
+func (f Format) Machine() bool {
+	return f != Text
+}
+
+// Table is implemented by results that can be printed as TSV
+type Table interface {
+	// Header names the columns
+	Header() []string
+	// Rows returns one row of values per record
+	Rows() [][]string
+}
+
+// Render writes v to w in a machine format. TSV requires v to implement Table.
+func Render(w io.Writer, f Format, v interface{}) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case TSV:
+		table, ok := v.(Table)
+		if !ok {
+			return fmt.Errorf("tsv output is not available here, use json or yaml")
+		}
+		if err := writeRow(w, table.Header()); err != nil {
+			return err
+		}
+		for _, row := range table.Rows() {
+			if err := writeRow(w, row); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%s is not a machine-readable format", f)
+	}
+}
+
+// tsvEscaper keeps every value on one line and in one column
+var tsvEscaper = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+func writeRow(w io.Writer, values []string) error {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = tsvEscaper.Replace(v)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(escaped, "\t"))
+	return err
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testRecord struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+type testTable []testRecord
+
+func (t testTable) Header() []string { return []string{"name", "count"} }
+
+func (t testTable) Rows() [][]string {
+	var rows [][]string
+	for _, r := range t {
+		rows = append(rows, []string{r.Name, string(rune('0' + r.Count))})
+	}
+	return rows
+}
+
+func TestRender(t *testing.T) {
+	records := testTable{{Name: "fix-bug", Count: 2}, {Name: "tab\there", Count: 0}}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{JSON, "[\n  {\n    \"name\": \"fix-bug\",\n    \"count\": 2\n  },\n  {\n    \"name\": \"tab\\there\",\n    \"count\": 0\n  }\n]\n"},
+		{YAML, "- name: fix-bug\n  count: 2\n- name: \"tab\\there\"\n  count: 0\n"},
+		{TSV, "name\tcount\nfix-bug\t2\ntab here\t0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tt.format, records); err != nil {
+				t.Fatalf("Render() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Render() = %q, expected %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	// Values without a tabular form cannot be printed as TSV
+	if err := Render(&bytes.Buffer{}, TSV, testRecord{Name: "x"}); err == nil {
+		t.Error("Render() should refuse TSV for a non-tabular value")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, expected := range map[string]Format{"": Text, "text": Text, "JSON": JSON, "yaml": YAML, "tsv": TSV} {
+		f, err := ParseFormat(input)
+		if err != nil || f != expected {
+			t.Errorf("ParseFormat(%q) = %q, %v; expected %q", input, f, err, expected)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat() should reject unknown formats")
+	}
+	if Text.Machine() || !JSON.Machine() {
+		t.Error("Machine() should only be false for text")
+	}
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/config"
+)
+
+// CarriedFile describes a file brought over from the main repository into a
+// new worktree
+type CarriedFile struct {
+	// Path is relative to the repository root
+	Path string
+	// Mode is how the file was brought over, "copy" or "symlink"
+	Mode string
+}
+
+// carryOver brings files matching the configured patterns from the main
+// repository into a freshly created worktree. Files that already exist in
+// the worktree, typically because git tracks them, are left alone, and
+// nothing outside the repository is ever brought over.
+func carryOver(srcRoot, dstRoot string, co config.CarryOver) ([]CarriedFile, error) {
+	mode := co.Mode
+	if mode == "" {
+		mode = config.CarryOverCopy
+	}
+	if mode != config.CarryOverCopy && mode != config.CarryOverSymlink {
+		return nil, fmt.Errorf("invalid carry_over mode %q, expected %q or %q", mode, config.CarryOverCopy, config.CarryOverSymlink)
+	}
+	if err := co.Validate(); err != nil {
+		return nil, err
+	}
+
+	var carried []CarriedFile
+	seen := map[string]bool{}
+
+	for _, pattern := range co.Patterns {
+		matches, err := filepath.Glob(filepath.Join(srcRoot, pattern))
+		if err != nil {
+			return carried, fmt.Errorf("invalid carry_over pattern %q: %w", pattern, err)
+		}
+
+		for _, src := range matches {
+			rel, err := filepath.Rel(srcRoot, src)
+			if err != nil || seen[rel] || rel == ".git" || !isInside(rel) {
+				continue
+			}
+			seen[rel] = true
+
+			dst := filepath.Join(dstRoot, rel)
+			if _, err := os.Lstat(dst); err == nil {
+				continue
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return carried, err
+			}
+
+			if mode == config.CarryOverSymlink {
+				err = os.Symlink(src, dst)
+			} else {
+				err = copyPath(src, dst)
+			}
+			if err != nil {
+				return carried, fmt.Errorf("failed to carry over %s: %w", rel, err)
+			}
+
+			carried = append(carried, CarriedFile{Path: rel, Mode: mode})
+		}
+	}
+
+	return carried, nil
+}
+
+// isInside reports whether a path relative to the repository root stays
+// inside it
+func isInside(rel string) bool {
+	return !filepath.IsAbs(rel) && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyPath copies a file, symlink or directory tree, preserving permissions
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm) // #nosec G304
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
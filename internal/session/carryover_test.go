@@ -0,0 +1,118 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/config"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestCarryOverCopy(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	writeTestFile(t, filepath.Join(src, ".env"), "SECRET=1")
+	writeTestFile(t, filepath.Join(src, "config", "local.yaml"), "debug: true")
+	writeTestFile(t, filepath.Join(src, ".claude", "settings.local.json"), "{}")
+	writeTestFile(t, filepath.Join(src, "README.md"), "main copy")
+	writeTestFile(t, filepath.Join(dst, "README.md"), "tracked copy")
+
+	carried, err := carryOver(src, dst, config.CarryOver{
+		Patterns: []string{".env", "config/*.yaml", ".claude/settings.local.json", "README.md", "missing.txt"},
+		Mode:     config.CarryOverCopy,
+	})
+	if err != nil {
+		t.Fatalf("carryOver() failed: %v", err)
+	}
+
+	if len(carried) != 3 {
+		t.Fatalf("carryOver() carried %d files, expected 3: %+v", len(carried), carried)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "config", "local.yaml"))
+	if err != nil || string(data) != "debug: true" {
+		t.Errorf("config/local.yaml = %q, %v", string(data), err)
+	}
+
+	info, err := os.Stat(filepath.Join(dst, ".env"))
+	if err != nil {
+		t.Fatalf(".env was not copied: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf(".env permissions = %v, expected 0600", info.Mode().Perm())
+	}
+
+	// Files already in the worktree must not be overwritten
+	data, _ = os.ReadFile(filepath.Join(dst, "README.md"))
+	if string(data) != "tracked copy" {
+		t.Errorf("README.md was overwritten: %q", string(data))
+	}
+}
+
+func TestCarryOverSymlink(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	writeTestFile(t, filepath.Join(src, ".env"), "SECRET=1")
+
+	carried, err := carryOver(src, dst, config.CarryOver{
+		Patterns: []string{".env"},
+		Mode:     config.CarryOverSymlink,
+	})
+	if err != nil {
+		t.Fatalf("carryOver() failed: %v", err)
+	}
+	if len(carried) != 1 || carried[0].Mode != config.CarryOverSymlink {
+		t.Fatalf("carryOver() = %+v, expected one symlinked file", carried)
+	}
+
+	target, err := os.Readlink(filepath.Join(dst, ".env"))
+	if err != nil {
+		t.Fatalf(".env is not a symlink: %v", err)
+	}
+	if target != filepath.Join(src, ".env") {
+		t.Errorf(".env links to %q, expected %q", target, filepath.Join(src, ".env"))
+	}
+}
+
+func TestCarryOverInvalidMode(t *testing.T) {
+	_, err := carryOver(t.TempDir(), t.TempDir(), config.CarryOver{
+		Patterns: []string{".env"},
+		Mode:     "hardlink",
+	})
+	if err == nil {
+		t.Error("carryOver() should reject an unknown mode")
+	}
+}
+
+func TestCarryOverStaysInsideRepository(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "repo")
+	dst := filepath.Join(root, "worktree")
+	writeTestFile(t, filepath.Join(src, ".env"), "SECRET=1")
+	writeTestFile(t, filepath.Join(root, ".ssh", "id_ed25519"), "private key")
+
+	for _, pattern := range []string{"../.ssh/*", "config/../../.ssh/id_ed25519", filepath.Join(root, ".ssh", "id_ed25519")} {
+		carried, err := carryOver(src, dst, config.CarryOver{Patterns: []string{".env", pattern}, Mode: config.CarryOverCopy})
+		if err == nil {
+			t.Errorf("carryOver() accepted the pattern %q, which reaches outside the repository", pattern)
+		}
+		if len(carried) != 0 {
+			t.Errorf("carryOver() with %q carried %+v", pattern, carried)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "worktree")); !os.IsNotExist(err) {
+		t.Error("carryOver() brought files over despite an escaping pattern")
+	}
+}
@@ -0,0 +1,188 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+)
+
+// Repository is a repository and its sessions, found without having to be
+// inside it
+type Repository struct {
+	Name     string            `json:"name" yaml:"name"`
+	ID       string            `json:"id" yaml:"id"`
+	Path     string            `json:"path" yaml:"path"`
+	Sessions []git.SessionInfo `json:"sessions" yaml:"sessions"`
+
+	// Manager operates on the repository's sessions
+	Manager *Manager `json:"-" yaml:"-"`
+}
+
+// StoredWorktree is a worktree in the shared worktree storage
+type StoredWorktree struct {
+	Path     string
+	MainRepo string
+}
+
+// StoredWorktrees lists the worktrees in the shared worktree storage
+// (~/.ccswitch/worktrees/<repo>/<session>), resolving each one's main
+// repository from its .git file so this works from any directory.
+// Directories git no longer knows about are left out; 'ccswitch doctor'
+// reports those.
+func StoredWorktrees() ([]StoredWorktree, error) {
+	root, err := layout.Root()
+	if err != nil {
+		return nil, err
+	}
+	repoDirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []StoredWorktree
+	for _, repoDir := range repoDirs {
+		if !repoDir.IsDir() {
+			continue
+		}
+		sessionDirs, err := os.ReadDir(filepath.Join(root, repoDir.Name()))
+		if err != nil {
+			continue
+		}
+		for _, sessionDir := range sessionDirs {
+			path := filepath.Join(root, repoDir.Name(), sessionDir.Name())
+			gitDir, err := git.WorktreeGitDir(path)
+			if err != nil {
+				continue
+			}
+			mainRepo, err := git.MainRepoPathFromWorktree(path)
+			if err != nil {
+				continue
+			}
+			if _, err := os.Stat(gitDir); err != nil {
+				continue
+			}
+			if _, err := os.Stat(mainRepo); err != nil {
+				continue
+			}
+			worktrees = append(worktrees, StoredWorktree{Path: path, MainRepo: mainRepo})
+		}
+	}
+	return worktrees, nil
+}
+
+// DiscoverRepositories finds the main repositories of the worktrees in the
+// shared worktree storage
+func DiscoverRepositories() ([]string, error) {
+	worktrees, err := StoredWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var repos []string
+	for _, wt := range worktrees {
+		if !seen[wt.MainRepo] {
+			seen[wt.MainRepo] = true
+			repos = append(repos, wt.MainRepo)
+		}
+	}
+
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// ListAllSessions lists the sessions of every repository found by
+// DiscoverRepositories. The repository containing currentDir, if any, is
+// included even when none of its sessions are in the shared storage. Every
+// repository's Manager runs git through client.
+func ListAllSessions(client git.Client, currentDir string) ([]Repository, error) {
+	paths, err := DiscoverRepositories()
+	if err != nil {
+		return nil, err
+	}
+	if client.IsGitRepository(currentDir) {
+		if mainRepo, err := client.GetMainRepoPath(currentDir); err == nil {
+			paths = append(paths, mainRepo)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var repos []Repository
+	for _, path := range paths {
+		m := NewManagerWithClient(client, path)
+		if seen[m.repoID] {
+			continue
+		}
+		seen[m.repoID] = true
+
+		sessions, err := m.ListSessions()
+		if err != nil {
+			continue
+		}
+		repos = append(repos, Repository{Name: m.repoName, ID: m.repoID, Path: m.mainRepoPath, Sessions: sessions, Manager: m})
+	}
+
+	sort.SliceStable(repos, func(i, j int) bool {
+		if repos[i].Name != repos[j].Name {
+			return repos[i].Name < repos[j].Name
+		}
+		return repos[i].ID < repos[j].ID
+	})
+	return repos, nil
+}
+
+// Label names the repository for display: its name, or its ID when another
+// listed repository has the same name
+func (r *Repository) Label(repos []Repository) string {
+	for _, other := range repos {
+		if other.Name == r.Name && other.ID != r.ID {
+			return r.ID
+		}
+	}
+	return r.Name
+}
+
+// FindInRepositories looks up a session given as "<repo>/<session>", where
+// repo is a repository's name or ID and session a session's name or branch
+func FindInRepositories(repos []Repository, ref string) (*Repository, *git.SessionInfo, error) {
+	repoRef, sessionRef, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q is not of the form <repo>/<session>", errors.ErrSessionNotFound, ref)
+	}
+
+	var matches []*Repository
+	for i := range repos {
+		if repos[i].ID == repoRef {
+			matches = []*Repository{&repos[i]}
+			break
+		}
+		if repos[i].Name == repoRef {
+			matches = append(matches, &repos[i])
+		}
+	}
+	if len(matches) > 1 {
+		ids := make([]string, len(matches))
+		for i, r := range matches {
+			ids[i] = r.ID
+		}
+		return nil, nil, fmt.Errorf("several repositories are named %s; use one of %s", repoRef, strings.Join(ids, ", "))
+	}
+
+	for _, repo := range matches {
+		for i, s := range repo.Sessions {
+			if s.Name == sessionRef || s.Branch == sessionRef {
+				return repo, &repo.Sessions[i], nil
+			}
+		}
+	}
+	return nil, nil, errors.Wrap(errors.ErrSessionNotFound, ref)
+}
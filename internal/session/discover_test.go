@@ -0,0 +1,64 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+)
+
+func TestListAllSessions(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// Two clones that share a directory name, plus one more repository
+	work := filepath.Join(tempDir, "work", "api")
+	oss := filepath.Join(tempDir, "oss", "api")
+	web := filepath.Join(tempDir, "web")
+	for _, dir := range []string{work, oss, web} {
+		initTestRepo(t, dir)
+		if _, err := NewManager(dir).CreateSession(CreateOptions{Description: "Fix bug"}); err != nil {
+			t.Fatalf("CreateSession() in %s failed: %v", dir, err)
+		}
+	}
+
+	// Listed from outside any repository
+	repos, err := ListAllSessions(git.Default(), tempDir)
+	if err != nil {
+		t.Fatalf("ListAllSessions() failed: %v", err)
+	}
+	if len(repos) != 3 {
+		t.Fatalf("ListAllSessions() found %d repositories, expected 3", len(repos))
+	}
+	for _, repo := range repos {
+		if findByName(repo.Sessions, "fix-bug") == nil {
+			t.Errorf("repository %s is missing its session: %+v", repo.ID, repo.Sessions)
+		}
+	}
+
+	// Same-named repositories are told apart by ID
+	if label := repos[2].Label(repos); label != "web" {
+		t.Errorf("Label() = %q, expected web", label)
+	}
+	if label := repos[0].Label(repos); label != repos[0].ID {
+		t.Errorf("Label() = %q, expected the ID of an ambiguous repository", label)
+	}
+
+	repo, s, err := FindInRepositories(repos, "web/fix-bug")
+	if err != nil || repo.Name != "web" || s.Branch != "feature/fix-bug" {
+		t.Errorf("FindInRepositories(web/fix-bug) = %v, %+v, %v", repo, s, err)
+	}
+	if _, _, err := FindInRepositories(repos, repos[1].ID+"/feature/fix-bug"); err != nil {
+		t.Errorf("FindInRepositories() by ID and branch failed: %v", err)
+	}
+	if _, _, err := FindInRepositories(repos, "api/fix-bug"); err == nil {
+		t.Error("FindInRepositories() should refuse an ambiguous repository name")
+	}
+	if _, _, err := FindInRepositories(repos, "web/nope"); !errors.IsSessionNotFound(err) {
+		t.Errorf("FindInRepositories(web/nope) = %v, expected session not found", err)
+	}
+}
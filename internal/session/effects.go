@@ -0,0 +1,88 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/hooks"
+	"github.com/ksred/ccswitch/internal/ui"
+)
+
+// SetDryRun makes the manager write the git commands and filesystem
+// changes it would make to out instead of making them. Git commands that
+// only look at the repository still run, so checks behave as they would.
+func (m *Manager) SetDryRun(out io.Writer) {
+	m.dryRun = out
+	m.useClient(m.git.DryRun(out))
+}
+
+// IsDryRun reports whether the manager only describes its changes
+func (m *Manager) IsDryRun() bool {
+	return m.dryRun != nil
+}
+
+// SetTrace makes the manager write each filesystem change it makes to out,
+// with how long it took. Git commands are traced by the client's runner.
+func (m *Manager) SetTrace(out io.Writer) {
+	m.trace = out
+}
+
+// change makes one change to the filesystem, which what describes. In a
+// dry run the change is only described.
+func (m *Manager) change(what string, fn func() error) error {
+	if m.dryRun != nil {
+		fmt.Fprintf(m.dryRun, "[dry-run] %s\n", what)
+		return nil
+	}
+	if m.trace == nil {
+		return fn()
+	}
+
+	start := time.Now()
+	err := fn()
+	took := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		fmt.Fprintf(m.trace, "+ %s (%s, failed)\n", what, took)
+	} else {
+		fmt.Fprintf(m.trace, "+ %s (%s)\n", what, took)
+	}
+	return err
+}
+
+// runHooks runs the hooks for event, which a dry run only lists
+func (m *Manager) runHooks(event hooks.Event, dir string, ctx hooks.Context) error {
+	m.warnUntrusted(event)
+	if m.dryRun != nil {
+		for _, command := range hooks.Commands(m.config.Hooks, event) {
+			fmt.Fprintf(m.dryRun, "[dry-run] run %s hook in %s: %s\n", event, dir, command)
+		}
+		return nil
+	}
+	return hooks.Run(m.config.Hooks, event, dir, ctx)
+}
+
+// runPostHooks runs the hooks for an event after the operation succeeded,
+// reporting rather than returning failures
+func (m *Manager) runPostHooks(event hooks.Event, dir string, ctx hooks.Context) {
+	if m.dryRun != nil {
+		_ = m.runHooks(event, dir, ctx)
+		return
+	}
+	m.warnUntrusted(event)
+	hooks.RunPost(m.config.Hooks, event, dir, ctx)
+}
+
+// warnUntrusted points out the repository's hooks for event that were left
+// out because the user hasn't trusted its config file
+func (m *Manager) warnUntrusted(event hooks.Event) {
+	skipped := hooks.Commands(m.config.UntrustedHooks, event)
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, ui.WarningStyle.Render(fmt.Sprintf(
+		"⚠️  Skipped %d %s hook(s) from %s; review the file and run 'ccswitch trust' to let them run",
+		len(skipped), event, config.RepoConfigFile)))
+}
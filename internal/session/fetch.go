@@ -0,0 +1,123 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// fetchCache records when each remote of a repository was last fetched
+type fetchCache map[string]time.Time
+
+// fetchCachePath returns the fetch cache of the manager's repository
+func (m *Manager) fetchCachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "fetch", m.repoID+".json"), nil
+}
+
+func (m *Manager) loadFetchCache() fetchCache {
+	cache := fetchCache{}
+	path, err := m.fetchCachePath()
+	if err != nil {
+		return cache
+	}
+	if data, err := os.ReadFile(path); err == nil { // #nosec G304
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// recordFetch notes that remotes were just fetched. The cache only saves
+// work, so failing to write it is not an error. A dry run fetched nothing.
+func (m *Manager) recordFetch(remotes ...string) {
+	if m.IsDryRun() {
+		return
+	}
+	path, err := m.fetchCachePath()
+	if err != nil {
+		return
+	}
+	cache := m.loadFetchCache()
+	now := time.Now()
+	for _, remote := range remotes {
+		cache[remote] = now
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		_ = utils.WriteFileAtomic(path, data)
+	}
+}
+
+// Fetch fetches every remote of the repository, whether or not
+// git.auto_fetch is set
+func (m *Manager) Fetch() error {
+	if err := m.git.Fetch(m.mainRepoPath); err != nil {
+		return err
+	}
+	m.recordFetch(m.git.Remotes(m.mainRepoPath)...)
+	return nil
+}
+
+// AutoFetch fetches the remote relevant to branch, or to the default
+// branch when branch is empty, if git.auto_fetch is set and the remote
+// wasn't fetched within git.fetch_interval. It returns the remote it
+// fetched or tried to fetch, or "" when it had no reason to. A failure,
+// typically from being offline, leaves the cache alone so the next command
+// tries again.
+func (m *Manager) AutoFetch(branch string) (string, error) {
+	if !m.config.Git.AutoFetch {
+		return "", nil
+	}
+	remote := m.fetchRemote(branch)
+	if remote == "" {
+		return "", nil
+	}
+
+	window, err := m.config.FetchWindow()
+	if err != nil {
+		return "", err
+	}
+	if last, ok := m.loadFetchCache()[remote]; ok && time.Since(last) < window {
+		return "", nil
+	}
+
+	if err := m.git.FetchRemote(m.mainRepoPath, remote); err != nil {
+		return remote, err
+	}
+	m.recordFetch(remote)
+	return remote, nil
+}
+
+// fetchRemote picks the remote to fetch for branch: the one it tracks, else
+// the one the default branch tracks, else origin or the only remote
+func (m *Manager) fetchRemote(branch string) string {
+	for _, b := range []string{branch, m.DefaultBranch()} {
+		if b == "" {
+			continue
+		}
+		// "." is the repository itself
+		if remote, _ := m.branchManager.Tracking(b); remote != "" && remote != "." {
+			return remote
+		}
+	}
+
+	remotes := m.git.Remotes(m.mainRepoPath)
+	for _, remote := range remotes {
+		if remote == "origin" {
+			return remote
+		}
+	}
+	if len(remotes) == 1 {
+		return remotes[0]
+	}
+	return ""
+}
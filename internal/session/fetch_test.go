@@ -0,0 +1,84 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoFetch(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// A repository cloned from a bare remote, and a second clone that pushes
+	remote := filepath.Join(tempDir, "remote.git")
+	repo := filepath.Join(tempDir, "api")
+	other := filepath.Join(tempDir, "other")
+	initTestRepo(t, repo)
+	runGit(t, tempDir, "clone", "--quiet", "--bare", repo, remote)
+	runGit(t, repo, "remote", "add", "origin", remote)
+	runGit(t, repo, "fetch", "--quiet", "origin")
+	runGit(t, repo, "branch", "--set-upstream-to=origin/main", "main")
+	runGit(t, tempDir, "clone", "--quiet", remote, other)
+	runGit(t, other, "config", "user.email", "test@example.com")
+	runGit(t, other, "config", "user.name", "Test User")
+	push := func(message string) string {
+		t.Helper()
+		runGit(t, other, "commit", "--allow-empty", "-m", message)
+		runGit(t, other, "push", "--quiet", "origin", "main")
+		commit, err := NewManager(other).branchManager.ResolveCommit("HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commit
+	}
+	originMain := func(m *Manager) string {
+		commit, _ := m.branchManager.ResolveCommit("origin/main")
+		return commit
+	}
+
+	// Off by default
+	pushed := push("First")
+	m := NewManager(repo)
+	if fetched, err := m.AutoFetch(""); fetched != "" || err != nil {
+		t.Errorf("AutoFetch() without git.auto_fetch = %q, %v; expected nothing", fetched, err)
+	}
+
+	writeTestFile(t, filepath.Join(tempDir, ".ccswitch", "config.yaml"), "git:\n  auto_fetch: true\n  fetch_interval: 1h\n")
+	m = NewManager(repo)
+	if fetched, err := m.AutoFetch(""); fetched != "origin" || err != nil {
+		t.Fatalf("AutoFetch() = %q, %v; expected origin fetched", fetched, err)
+	}
+	if originMain(m) != pushed {
+		t.Error("origin/main should include the pushed commit")
+	}
+
+	// A new session starts from origin/main, which is ahead of main
+	result, err := m.CreateSession(CreateOptions{Description: "Fresh"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	if result.Session.BaseBranch != "origin/main" || result.Session.BaseCommit != pushed {
+		t.Errorf("session based on %s at %s, expected origin/main at %s", result.Session.BaseBranch, result.Session.BaseCommit, pushed)
+	}
+
+	// Within the interval nothing is fetched
+	push("Second")
+	if fetched, err := m.AutoFetch(""); fetched != "" || err != nil || originMain(m) != pushed {
+		t.Errorf("AutoFetch() within the interval = %q, %v; expected nothing fetched", fetched, err)
+	}
+
+	// An unreachable remote is reported, and not remembered as fetched
+	writeTestFile(t, filepath.Join(tempDir, ".ccswitch", "config.yaml"), "git:\n  auto_fetch: true\n  fetch_interval: \"0\"\n")
+	runGit(t, repo, "remote", "set-url", "origin", filepath.Join(tempDir, "gone.git"))
+	m = NewManager(repo)
+	if fetched, err := m.AutoFetch(""); fetched != "origin" || err == nil {
+		t.Errorf("AutoFetch() offline = %q, %v; expected an error", fetched, err)
+	}
+	runGit(t, repo, "remote", "set-url", "origin", remote)
+	if _, err := m.AutoFetch(""); err != nil || originMain(m) == pushed {
+		t.Errorf("AutoFetch() back online failed (%v) or fetched nothing", err)
+	}
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Loss is the work removing a session would throw away
+type Loss struct {
+	// Changed are modified, staged and conflicted files, with the status
+	// letters git status --short shows them with
+	Changed   []string `json:"changed,omitempty" yaml:"changed,omitempty"`
+	Untracked []string `json:"untracked,omitempty" yaml:"untracked,omitempty"`
+	// Commits are on no other branch, remote branch or tag, so deleting the
+	// branch loses them
+	Commits []string `json:"commits,omitempty" yaml:"commits,omitempty"`
+}
+
+// Empty reports whether nothing would be lost
+func (l *Loss) Empty() bool {
+	return len(l.Changed) == 0 && len(l.Untracked) == 0 && len(l.Commits) == 0
+}
+
+// String summarizes the loss, e.g. "2 changed files and 1 unpushed commit"
+func (l *Loss) String() string {
+	var parts []string
+	if n := len(l.Changed); n > 0 {
+		parts = append(parts, plural(n, "changed file"))
+	}
+	if n := len(l.Untracked); n > 0 {
+		parts = append(parts, plural(n, "untracked file"))
+	}
+	if n := len(l.Commits); n > 0 {
+		parts = append(parts, plural(n, "unpushed commit"))
+	}
+	switch len(parts) {
+	case 0:
+		return "nothing"
+	case 1:
+		return parts[0]
+	default:
+		return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// CheckRemoval finds the work removing the session at sessionPath would
+// throw away: uncommitted changes and untracked files and, when its branch
+// is deleted too, commits that exist nowhere else. Commits squash-merged
+// into the default branch have landed and don't count.
+func (m *Manager) CheckRemoval(sessionPath, branchName string, deleteBranch bool) (*Loss, error) {
+	loss := &Loss{}
+
+	// A worktree whose directory is gone has nothing left to lose
+	if _, err := os.Stat(sessionPath); err == nil {
+		changed, untracked, err := m.git.UncommittedFiles(sessionPath)
+		if err != nil {
+			return nil, err
+		}
+		loss.Changed, loss.Untracked = changed, untracked
+	}
+
+	if deleteBranch && branchName != "" && m.branchManager.Exists(branchName) {
+		commits, err := m.branchManager.UniqueCommits(branchName)
+		if err != nil {
+			return nil, err
+		}
+		if len(commits) > 0 {
+			if defaultRef := m.defaultBranchRef(); defaultRef != "" {
+				if merged, err := m.git.SquashMerged(sessionPath, defaultRef); err == nil && merged {
+					commits = nil
+				}
+			}
+		}
+		loss.Commits = commits
+	}
+
+	return loss, nil
+}
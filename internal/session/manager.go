@@ -2,150 +2,412 @@ package session
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/ksred/ccswitch/internal/config"
 	"github.com/ksred/ccswitch/internal/errors"
 	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/hooks"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/lock"
+	"github.com/ksred/ccswitch/internal/state"
+	"github.com/ksred/ccswitch/internal/ui"
 	"github.com/ksred/ccswitch/internal/utils"
 )
 
 // Manager handles session operations
 type Manager struct {
+	git             git.Client
 	worktreeManager *git.WorktreeManager
 	branchManager   *git.BranchManager
 	config          *config.Config
+	store           *state.Store
+	legacyStore     *state.Store
+	layout          *layout.Layout
+	layoutErr       error
 	repoPath        string
+	mainRepoPath    string
 	repoName        string
+	repoID          string
+	defaultBranch   string
+	heldLock        *lock.Lock
+	lockDepth       int
+	// dryRun and trace receive the changes the manager would make, or makes;
+	// see SetDryRun and SetTrace
+	dryRun io.Writer
+	trace  io.Writer
 }
 
-// NewManager creates a new session manager
+// NewManager creates a new session manager that runs the git binary
 func NewManager(repoPath string) *Manager {
+	return NewManagerWithClient(git.Default(), repoPath)
+}
+
+// NewManagerWithClient creates a new session manager that runs git through
+// client
+func NewManagerWithClient(client git.Client, repoPath string) *Manager {
 	// Get the main repository path to ensure we list all worktrees
-	mainRepoPath, err := git.GetMainRepoPath(repoPath)
+	mainRepoPath, err := client.GetMainRepoPath(repoPath)
 	if err != nil {
 		// Fallback to the provided path if we can't get the main repo
 		mainRepoPath = repoPath
 	}
 
 	repoName := filepath.Base(mainRepoPath)
-	cfg, _ := config.Load()
+	repoID, err := client.GetRepoID(repoPath)
+	if err != nil {
+		repoID = repoName
+	}
+	repo := layout.Repo{Name: repoName, ID: repoID, Path: mainRepoPath}
+
+	cfg, _ := config.LoadForRepo(mainRepoPath)
+	store, _ := state.NewStore(repoID)
+
+	// State written before repositories had IDs, adopted by adoptRecords
+	var legacyStore *state.Store
+	if repoID != repoName {
+		legacyStore, _ = state.NewStore(repoName)
+	}
+
+	// A broken layout must not stop us from listing or cleaning up existing
+	// sessions, so fall back to the default and only refuse to create new ones
+	wtLayout, layoutErr := layout.New(cfg, repo)
+	if layoutErr != nil {
+		wtLayout, _ = layout.FromTemplate(layout.CentralizedTemplate, repo, cfg.Worktree.RelativePath)
+	}
 
 	return &Manager{
-		worktreeManager: git.NewWorktreeManager(mainRepoPath),
-		branchManager:   git.NewBranchManager(repoPath), // Keep current path for branch operations
+		git:             client,
+		worktreeManager: git.NewWorktreeManager(client, mainRepoPath),
+		branchManager:   git.NewBranchManager(client, repoPath), // Keep current path for branch operations
 		config:          cfg,
+		store:           store,
+		legacyStore:     legacyStore,
+		layout:          wtLayout,
+		layoutErr:       layoutErr,
 		repoPath:        repoPath,
+		mainRepoPath:    mainRepoPath,
 		repoName:        repoName,
+		repoID:          repoID,
 	}
 }
 
-// CreateSession creates a new work session
-func (m *Manager) CreateSession(description string) error {
-	branchName := m.config.Branch.Prefix + utils.Slugify(description)
+// CreateOptions controls how a new session is created
+type CreateOptions struct {
+	// Description is what the user is working on; it names the session and branch
+	Description string
+	// From is the branch, tag or commit to start from. Empty means the
+	// default branch.
+	From string
+	// BranchName overrides the branch name derived from the description
+	BranchName string
+}
+
+// Result describes a session that was just created or checked out
+type Result struct {
+	Session git.SessionInfo
+	// CarriedOver lists the untracked files brought over from the main repository
+	CarriedOver []CarriedFile
+}
+
+// CreateSession creates a new work session. It runs as a transaction: if
+// any step fails or is interrupted, every earlier step is undone.
+func (m *Manager) CreateSession(opts CreateOptions) (*Result, error) {
+	description := opts.Description
+	branchName := m.BranchNameFor(opts)
 	sessionName := utils.Slugify(description)
+	if sessionName == "" {
+		return nil, fmt.Errorf("description %q must contain letters or numbers", description)
+	}
+
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	// Check if we're already on the branch we want to create
 	currentBranch, err := m.branchManager.GetCurrent()
 	if err == nil && currentBranch == branchName {
-		return fmt.Errorf("%w: %s", errors.ErrAlreadyOnBranch, branchName)
+		return nil, fmt.Errorf("%w: %s", errors.ErrAlreadyOnBranch, branchName)
 	}
 
 	// Check if branch already exists
 	if m.branchManager.Exists(branchName) {
-		return fmt.Errorf("%w: %s", errors.ErrBranchExists, branchName)
+		return nil, fmt.Errorf("%w: %s", errors.ErrBranchExists, branchName)
 	}
 
 	// Get worktree path
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return errors.Wrap(err, "failed to get home directory")
+	if m.layoutErr != nil {
+		return nil, m.layoutErr
 	}
+	worktreePath := m.layout.SessionPath(sessionName)
 
-	// Get repo name from the main repo path
-	mainRepoPath, err := git.GetMainRepoPath(m.repoPath)
-	if err != nil {
-		mainRepoPath = m.repoPath
+	// Check if worktree directory already exists
+	if _, err := os.Stat(worktreePath); err == nil {
+		return nil, fmt.Errorf("%w: %s", errors.ErrWorktreeExists, worktreePath)
 	}
-	repoName := filepath.Base(mainRepoPath)
 
-	worktreeBasePath := filepath.Join(homeDir, ".ccswitch", "worktrees", repoName)
-	worktreePath := filepath.Join(worktreeBasePath, sessionName)
+	// Work out where the new branch starts so other commands can find it later
+	baseBranch, err := m.resolveBase(opts.From)
+	if err != nil {
+		return nil, err
+	}
+	baseCommit, err := m.branchManager.ResolveCommit(baseBranch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errors.ErrRefNotFound, baseBranch)
+	}
 
-	// Check if worktree directory already exists
-	if _, err := os.Stat(worktreePath); err == nil {
-		return fmt.Errorf("%w: %s", errors.ErrWorktreeExists, worktreePath)
+	tx, err := m.begin("create", sessionName)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure the worktree base directory exists
-	if err := os.MkdirAll(worktreeBasePath, 0755); err != nil {
-		return errors.Wrap(err, "failed to create worktree directory")
+	if err := tx.mkdirAll(filepath.Dir(worktreePath)); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	hookCtx := hooks.Context{Session: sessionName, Branch: branchName, RepoRoot: m.mainRepoPath, Worktree: worktreePath}
+	if err := tx.run(func() error { return m.runHooks(hooks.PreCreate, m.mainRepoPath, hookCtx) }); err != nil {
+		return nil, tx.fail(err)
 	}
 
 	// Create branch
-	if err := m.branchManager.Create(branchName); err != nil {
-		return err
+	err = tx.step(undoAction{Kind: undoDeleteBranch, Branch: branchName, Commit: baseCommit}, func() error {
+		return m.branchManager.Create(branchName, baseCommit)
+	})
+	if err != nil {
+		return nil, tx.fail(err)
 	}
 
 	// Create worktree
-	if err := m.worktreeManager.Create(worktreePath, branchName); err != nil {
-		// Try to clean up the branch we just created
-		_ = m.branchManager.Delete(branchName, false)
-		return err
+	err = tx.step(undoAction{Kind: undoRemoveWorktree, Path: worktreePath}, func() error {
+		return m.worktreeManager.Create(worktreePath, branchName)
+	})
+	if err != nil {
+		return nil, tx.fail(err)
 	}
 
-	return nil
+	branchNamed := opts.BranchName == ""
+	result, err := m.finishSession(tx, state.Session{
+		Name:        sessionName,
+		Branch:      branchName,
+		BranchNamed: &branchNamed,
+		Path:        worktreePath,
+		Description: description,
+		BaseBranch:  baseBranch,
+		BaseCommit:  baseCommit,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Post hooks can be slow (npm ci and friends); don't hold others up
+	release()
+	m.runPostHooks(hooks.PostCreate, worktreePath, hookCtx)
+
+	return result, nil
+}
+
+// BranchNameFor returns the branch a session created with opts will use
+func (m *Manager) BranchNameFor(opts CreateOptions) string {
+	if opts.BranchName != "" {
+		return opts.BranchName
+	}
+	return m.config.Branch.Prefix + utils.Slugify(opts.Description)
+}
+
+// resolveBase picks the ref a new session branches from. An explicit ref must
+// exist; otherwise the configured default branch is used, locally or from
+// origin if that is newer, so sessions never silently stack on whatever
+// happens to be checked out.
+func (m *Manager) resolveBase(from string) (string, error) {
+	if from != "" {
+		if _, err := m.branchManager.ResolveCommit(from); err != nil {
+			return "", fmt.Errorf("%w: %s", errors.ErrRefNotFound, from)
+		}
+		return from, nil
+	}
+
+	if ref := m.LatestDefaultBranch(); ref != "" {
+		return ref, nil
+	}
+
+	// No default branch to be found; fall back to the old behaviour
+	currentBranch, err := m.branchManager.GetCurrent()
+	if err != nil || currentBranch == "" {
+		return "HEAD", nil
+	}
+	return currentBranch, nil
+}
+
+// useClient makes the manager run git through client from now on
+func (m *Manager) useClient(client git.Client) {
+	m.git = client
+	m.worktreeManager = git.NewWorktreeManager(client, m.mainRepoPath)
+	m.branchManager = git.NewBranchManager(client, m.repoPath)
+}
+
+// DefaultBranch returns the repository's default branch: git.default_branch
+// if set, otherwise the one git.Client.DefaultBranch detects
+func (m *Manager) DefaultBranch() string {
+	if m.defaultBranch == "" {
+		m.defaultBranch = m.git.DefaultBranch(m.mainRepoPath, m.config.Git.DefaultBranch)
+	}
+	return m.defaultBranch
 }
 
-// CheckoutSession creates a worktree for an existing branch
-func (m *Manager) CheckoutSession(branchName string) error {
+// IsProtected reports whether branch is the default branch or matches one
+// of git.protected_branches, which cleanup and pr must leave alone
+func (m *Manager) IsProtected(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	if branch == m.DefaultBranch() {
+		return true
+	}
+	for _, pattern := range m.config.Git.ProtectedBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultBranchRef returns the default branch, locally or from origin, or
+// "" if neither exists
+func (m *Manager) defaultBranchRef() string {
+	defaultBranch := m.DefaultBranch()
+	if m.branchManager.Exists(defaultBranch) {
+		return defaultBranch
+	}
+	if m.branchManager.RefExists("refs/remotes/origin/" + defaultBranch) {
+		return "origin/" + defaultBranch
+	}
+	return ""
+}
+
+// CheckoutSession creates a worktree for an existing branch. Like
+// CreateSession, it is undone completely if any step fails.
+func (m *Manager) CheckoutSession(branchName string) (*Result, error) {
 	sessionName := utils.Slugify(branchName)
 
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Check if branch exists
 	if !m.branchManager.Exists(branchName) {
-		return fmt.Errorf("%w: %s", errors.ErrBranchNotFound, branchName)
+		return nil, fmt.Errorf("%w: %s", errors.ErrBranchNotFound, branchName)
 	}
 
 	// Check if we're already on the branch we want to checkout
 	currentBranch, err := m.branchManager.GetCurrent()
 	if err == nil && currentBranch == branchName {
-		return fmt.Errorf("%w: %s", errors.ErrAlreadyOnBranch, branchName)
+		return nil, fmt.Errorf("%w: %s", errors.ErrAlreadyOnBranch, branchName)
 	}
 
 	// Get worktree path
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return errors.Wrap(err, "failed to get home directory")
+	if m.layoutErr != nil {
+		return nil, m.layoutErr
 	}
-
-	// Get repo name from the main repo path
-	mainRepoPath, err := git.GetMainRepoPath(m.repoPath)
-	if err != nil {
-		mainRepoPath = m.repoPath
-	}
-	repoName := filepath.Base(mainRepoPath)
-
-	worktreeBasePath := filepath.Join(homeDir, ".ccswitch", "worktrees", repoName)
-	worktreePath := filepath.Join(worktreeBasePath, sessionName)
+	worktreePath := m.layout.SessionPath(sessionName)
 
 	// Check if worktree directory already exists
 	if _, err := os.Stat(worktreePath); err == nil {
-		return fmt.Errorf("%w: %s", errors.ErrWorktreeExists, worktreePath)
+		return nil, fmt.Errorf("%w: %s", errors.ErrWorktreeExists, worktreePath)
+	}
+
+	tx, err := m.begin("checkout", sessionName)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure the worktree base directory exists
-	if err := os.MkdirAll(worktreeBasePath, 0755); err != nil {
-		return errors.Wrap(err, "failed to create worktree directory")
+	if err := tx.mkdirAll(filepath.Dir(worktreePath)); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	hookCtx := hooks.Context{Session: sessionName, Branch: branchName, RepoRoot: m.mainRepoPath, Worktree: worktreePath}
+	if err := tx.run(func() error { return m.runHooks(hooks.PreCreate, m.mainRepoPath, hookCtx) }); err != nil {
+		return nil, tx.fail(err)
 	}
 
 	// Create worktree for existing branch
-	if err := m.worktreeManager.Create(worktreePath, branchName); err != nil {
+	err = tx.step(undoAction{Kind: undoRemoveWorktree, Path: worktreePath}, func() error {
+		return m.worktreeManager.Create(worktreePath, branchName)
+	})
+	if err != nil {
+		return nil, tx.fail(err)
+	}
+
+	branchNamed := false
+	result, err := m.finishSession(tx, state.Session{
+		Name:        sessionName,
+		Branch:      branchName,
+		BranchNamed: &branchNamed,
+		Path:        worktreePath,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	release()
+	m.runPostHooks(hooks.PostCreate, worktreePath, hookCtx)
+
+	return result, nil
+}
+
+// finishSession completes a newly added worktree: it brings over untracked
+// files, records the session's metadata and commits the transaction.
+// Carried files need no undo of their own; they go with the worktree.
+func (m *Manager) finishSession(tx *transaction, rec state.Session) (*Result, error) {
+	var carried []CarriedFile
+	bringOver := func() error {
+		var err error
+		carried, err = carryOver(m.mainRepoPath, rec.Path, m.config.Worktree.CarryOver)
 		return err
 	}
+	err := tx.run(func() error {
+		if len(m.config.Worktree.CarryOver.Patterns) == 0 {
+			// Nothing to copy, so nothing worth describing
+			return bringOver()
+		}
+		return m.change("carry over untracked files into "+rec.Path, bringOver)
+	})
+	if err != nil {
+		return nil, tx.fail(err)
+	}
 
-	return nil
+	if err := tx.step(m.recordUndo(rec.Name), func() error { return m.recordSession(rec) }); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	if err := tx.commit(); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	return &Result{
+		Session: git.SessionInfo{
+			Name:        rec.Name,
+			Branch:      rec.Branch,
+			Path:        rec.Path,
+			Description: rec.Description,
+			BaseBranch:  rec.BaseBranch,
+			BaseCommit:  rec.BaseCommit,
+			CreatedAt:   rec.CreatedAt,
+		},
+		CarriedOver: carried,
+	}, nil
 }
 
 // ListSessions returns all active sessions
@@ -154,32 +416,425 @@ func (m *Manager) ListSessions() ([]git.SessionInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return git.GetSessionsFromWorktrees(worktrees, m.repoName), nil
+	sessions := git.GetSessionsFromWorktrees(worktrees, m.repoName, m.sessionMatchers()...)
+
+	// Merge in the metadata git cannot tell us about, without taking the
+	// lock, so listing never waits for another run. A broken state file
+	// should never stop the user from seeing their sessions.
+	if m.store == nil {
+		return sessions, nil
+	}
+	loaded := make(map[string]*state.File)
+	for i := range sessions {
+		_, rec := m.findRecord(sessions[i], loaded)
+		if rec == nil {
+			continue
+		}
+		sessions[i].Description = rec.Description
+		sessions[i].BaseBranch = rec.BaseBranch
+		sessions[i].BaseCommit = rec.BaseCommit
+		sessions[i].CreatedAt = rec.CreatedAt
+		sessions[i].LastSwitchedAt = rec.LastSwitchedAt
+	}
+
+	return sessions, nil
 }
 
-// RemoveSession removes a session and optionally its branch
-func (m *Manager) RemoveSession(sessionPath string, deleteBranch bool, branchName string) error {
+// recordStores returns the state files that may hold a session's metadata:
+// this repository's, then the one named after the storage directory the
+// session is in, if it was stored under an earlier ID, then the one older
+// versions kept by repository name
+func (m *Manager) recordStores(path string) []*state.Store {
+	stores := []*state.Store{m.store}
+	if dir, _, ok := layout.Stored(path); ok && dir != m.repoID && dir != m.repoName {
+		if store, err := state.NewStore(dir); err == nil {
+			stores = append(stores, store)
+		}
+	}
+	if m.legacyStore != nil {
+		stores = append(stores, m.legacyStore)
+	}
+	return stores
+}
+
+// findRecord returns a session's metadata and the store holding it, or nil
+// if none is recorded. State files are read once, into loaded; a broken one
+// is skipped.
+func (m *Manager) findRecord(s git.SessionInfo, loaded map[string]*state.File) (*state.Store, *state.Session) {
+	for _, store := range m.recordStores(s.Path) {
+		f, ok := loaded[store.Path()]
+		if !ok {
+			f, _ = store.Load()
+			loaded[store.Path()] = f
+		}
+		if f == nil {
+			continue
+		}
+		rec := f.FindByPath(s.Path)
+		// Only our own records are trusted by name; the others may be
+		// shared with clones of the same name
+		if rec == nil && store == m.store {
+			if r, ok := f.Sessions[s.Name]; ok && r.Branch == s.Branch {
+				rec = r
+			}
+		}
+		if rec != nil {
+			return store, rec
+		}
+	}
+	return nil, nil
+}
+
+// statusConcurrency bounds how many worktrees are read at once
+const statusConcurrency = 8
+
+// LoadStatuses fills in the git status of each session, reading worktrees
+// concurrently
+func (m *Manager) LoadStatuses(sessions []git.SessionInfo) {
+	m.git.LoadStatuses(sessions, m.defaultBranchRef(), statusConcurrency)
+}
+
+// SwitchSession runs the switch hooks for a session and records the switch.
+// The caller is responsible for actually changing directory.
+func (m *Manager) SwitchSession(session git.SessionInfo) error {
+	hookCtx := hooks.Context{Session: session.Name, Branch: session.Branch, RepoRoot: m.mainRepoPath, Worktree: session.Path}
+	if err := m.runHooks(hooks.PreSwitch, session.Path, hookCtx); err != nil {
+		return err
+	}
+
+	// Remember when this session was last used; not worth failing the switch over
+	_ = m.MarkSwitched(session)
+
+	m.runPostHooks(hooks.PostSwitch, session.Path, hookCtx)
+	return nil
+}
+
+// MarkSwitched records that the user switched to the given session
+func (m *Manager) MarkSwitched(session git.SessionInfo) error {
+	if m.store == nil {
+		return nil
+	}
+	release, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return m.change("record switch to session "+session.Name, func() error {
+		return m.store.Update(session.Name, func(rec *state.Session) {
+			rec.Branch = session.Branch
+			rec.Path = session.Path
+			rec.LastSwitchedAt = time.Now()
+		})
+	})
+}
+
+// FindSession returns the session with the given name or branch
+func (m *Manager) FindSession(name string) (*git.SessionInfo, error) {
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		if s.Name == name || s.Branch == name {
+			s := s // Create a copy to take address of
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errors.ErrSessionNotFound, name)
+}
+
+// RenameSession gives a session a new description, moving its worktree and
+// renaming its branch, when ccswitch named that after the session, to match.
+// If any step fails, the others are undone.
+func (m *Manager) RenameSession(name, description string) (*git.SessionInfo, error) {
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	current, err := m.FindSession(name)
+	if err != nil {
+		return nil, err
+	}
+	if current.Path == m.worktreeManager.RepoPath() {
+		return nil, fmt.Errorf("cannot rename the main repository")
+	}
+
+	newName := utils.Slugify(description)
+	if newName == "" {
+		return nil, fmt.Errorf("description %q must contain letters or numbers", description)
+	}
+	newPath := m.GetSessionPath(newName)
+
+	// Only a branch ccswitch named after the session follows it; one the
+	// user named, with --branch or by checking it out, keeps its name
+	branchNamed := m.branchNamed(*current)
+	newBranch := current.Branch
+	if branchNamed {
+		newBranch = m.config.Branch.Prefix + newName
+	}
+
+	if newBranch != current.Branch && m.branchManager.Exists(newBranch) {
+		return nil, fmt.Errorf("%w: %s", errors.ErrBranchExists, newBranch)
+	}
+	if newPath != current.Path {
+		if _, err := os.Stat(newPath); err == nil {
+			return nil, fmt.Errorf("%w: %s", errors.ErrWorktreeExists, newPath)
+		}
+	}
+
+	tx, err := m.begin("rename", current.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rename the branch first; it is the cheaper step to undo
+	if newBranch != current.Branch {
+		err := tx.step(undoAction{Kind: undoRenameBranch, From: newBranch, To: current.Branch}, func() error {
+			return m.branchManager.Rename(current.Branch, newBranch)
+		})
+		if err != nil {
+			return nil, tx.fail(err)
+		}
+	}
+
+	if newPath != current.Path {
+		if err := tx.mkdirAll(filepath.Dir(newPath)); err != nil {
+			return nil, tx.fail(err)
+		}
+		err := tx.step(undoAction{Kind: undoMoveWorktree, From: newPath, To: current.Path}, func() error {
+			return m.worktreeManager.Move(current.Path, newPath)
+		})
+		if err != nil {
+			return nil, tx.fail(err)
+		}
+	}
+
+	renamed := *current
+	renamed.Name = newName
+	renamed.Branch = newBranch
+	renamed.Path = newPath
+	renamed.Description = description
+
+	if m.store != nil {
+		rec := state.Session{
+			Name:           renamed.Name,
+			Branch:         renamed.Branch,
+			BranchNamed:    &branchNamed,
+			Path:           renamed.Path,
+			Description:    renamed.Description,
+			BaseBranch:     renamed.BaseBranch,
+			BaseCommit:     renamed.BaseCommit,
+			CreatedAt:      renamed.CreatedAt,
+			LastSwitchedAt: renamed.LastSwitchedAt,
+		}
+		err := tx.step(m.recordUndo(current.Name), func() error {
+			return m.change("forget session "+current.Name, func() error { return m.store.Delete(current.Name) })
+		})
+		if err == nil {
+			err = tx.step(m.recordUndo(rec.Name), func() error {
+				return m.change("record session "+rec.Name, func() error { return m.store.Put(rec) })
+			})
+		}
+		if err != nil {
+			return nil, tx.fail(errors.Wrap(err, "failed to record session metadata"))
+		}
+	}
+
+	if err := tx.commit(); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	return &renamed, nil
+}
+
+// branchNamed reports whether ccswitch named a session's branch after the
+// session. Records from before that was kept go by the branch's name.
+func (m *Manager) branchNamed(s git.SessionInfo) bool {
+	if m.store != nil {
+		if f, err := m.store.Load(); err == nil {
+			if rec := f.FindByPath(s.Path); rec != nil && rec.BranchNamed != nil {
+				return *rec.BranchNamed
+			}
+		}
+	}
+	return s.Branch == m.config.Branch.Prefix+s.Name
+}
+
+// RemoveOptions controls RemoveSession
+type RemoveOptions struct {
+	// Branch is the session's branch
+	Branch string
+	// DeleteBranch deletes Branch along with the worktree
+	DeleteBranch bool
+	// Force removes the session even if that throws away work; see
+	// CheckRemoval
+	Force bool
+}
+
+// RemoveSession removes a session and optionally its branch. Unless
+// opts.Force is set, it refuses with ErrUncommittedChanges when that would
+// throw away work. Either way the session is saved to the trash first, so
+// RestoreFromTrash can bring it back.
+func (m *Manager) RemoveSession(sessionPath string, opts RemoveOptions) error {
+	release, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	sessionName := filepath.Base(sessionPath)
+	var rec *state.Session
+	if m.store != nil {
+		if f, err := m.store.Load(); err == nil {
+			rec = f.FindByPath(sessionPath)
+		}
+	}
+	if rec != nil {
+		sessionName = rec.Name
+	}
+
+	if opts.DeleteBranch && m.IsProtected(opts.Branch) {
+		return fmt.Errorf("refusing to delete protected branch %s", opts.Branch)
+	}
+
+	if !opts.Force {
+		loss, err := m.CheckRemoval(sessionPath, opts.Branch, opts.DeleteBranch)
+		if err != nil {
+			return errors.Wrap(err, "failed to check for unsaved work")
+		}
+		if !loss.Empty() {
+			return fmt.Errorf("%w: removing %s would lose %s", errors.ErrUncommittedChanges, sessionName, loss)
+		}
+	}
+
+	hookCtx := hooks.Context{Session: sessionName, Branch: opts.Branch, RepoRoot: m.mainRepoPath, Worktree: sessionPath}
+	if err := m.runHooks(hooks.PreRemove, sessionPath, hookCtx); err != nil {
+		return err
+	}
+
+	trashed := state.Session{Name: sessionName, Branch: opts.Branch, Path: sessionPath}
+	if rec != nil {
+		trashed = *rec
+	}
+	entry, err := m.saveToTrash(trashed, opts.DeleteBranch)
+	if err != nil {
+		return errors.Wrap(err, "failed to save session to the trash")
+	}
+
 	// Remove worktree
-	if err := m.worktreeManager.Remove(sessionPath); err != nil {
+	if err := m.worktreeManager.Remove(sessionPath, opts.Force); err != nil {
+		if entry != nil {
+			_ = m.discardTrash(entry)
+		}
 		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
-	// Delete branch if requested
-	if deleteBranch && branchName != "" {
-		if err := m.branchManager.Delete(branchName, false); err != nil {
-			// Check if we need to force delete
-			if strings.Contains(err.Error(), "not fully merged") {
-				return m.branchManager.Delete(branchName, true)
-			}
+	// Delete branch if requested. Its commits were checked above, and git's
+	// own check would refuse squash-merged branches.
+	if opts.DeleteBranch && opts.Branch != "" {
+		if err := m.branchManager.Delete(opts.Branch, true); err != nil {
+			return err
+		}
+	}
+
+	if rec != nil {
+		if err := m.change("forget session "+rec.Name, func() error { return m.store.Delete(rec.Name) }); err != nil {
 			return err
 		}
 	}
 
+	release()
+	m.runPostHooks(hooks.PostRemove, m.mainRepoPath, hookCtx)
+	return nil
+}
+
+// recordSession persists metadata for a newly created session
+func (m *Manager) recordSession(rec state.Session) error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.change("record session "+rec.Name, func() error { return m.store.Put(rec) }); err != nil {
+		return errors.Wrap(err, "failed to record session metadata")
+	}
 	return nil
 }
 
 // GetSessionPath returns the path for a session
 func (m *Manager) GetSessionPath(sessionName string) string {
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".ccswitch", "worktrees", m.repoName, sessionName)
+	return m.layout.SessionPath(sessionName)
+}
+
+// lock takes the repository lock for a mutating operation, so parallel
+// ccswitch processes cannot both pass the same existence checks. It is
+// reentrant, and the returned release function may be called more than once
+// so callers can let go early, before running post hooks.
+func (m *Manager) lock() (func(), error) {
+	if m.lockDepth == 0 {
+		l, err := lock.Acquire(m.repoID, lock.DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+		m.heldLock = l
+
+		// Pin the repository's ID, so moving the repository later doesn't
+		// strand its sessions, state and trash under the old one
+		if !m.git.IsDryRun() {
+			_ = m.git.RecordRepoID(m.mainRepoPath)
+		}
+
+		// Holding the lock means no other run is mid-operation, so any
+		// journal left behind belongs to one that died
+		if err := m.recoverJournal(); err != nil {
+			ui.Warningf("⚠️  Could not fully revert an interrupted operation: %v", err)
+		}
+		// Listing leaves metadata kept under old names where it is; runs
+		// that change something move it over
+		m.adoptRecords()
+	}
+	m.lockDepth++
+
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		m.lockDepth--
+		if m.lockDepth == 0 {
+			_ = m.heldLock.Release()
+			m.heldLock = nil
+		}
+	}, nil
+}
+
+// IsMainRepo reports whether a session is the main repository rather than
+// a worktree ccswitch created
+func (m *Manager) IsMainRepo(s git.SessionInfo) bool {
+	return filepath.Clean(s.Path) == filepath.Clean(m.mainRepoPath)
+}
+
+// RepoID returns the identifier namespacing this repository's storage
+func (m *Manager) RepoID() string {
+	return m.repoID
+}
+
+// repo describes the repository for resolving layouts
+func (m *Manager) repo() layout.Repo {
+	return layout.Repo{Name: m.repoName, ID: m.repoID, Path: m.mainRepoPath}
+}
+
+// sessionMatchers recognizes sessions under the configured layout and
+// anywhere in the worktree storage, whatever directory they are in, so
+// neither changing layouts nor an older ID or storage scheme hides existing
+// sessions. Only worktrees registered with this repository are matched, so
+// a shared directory cannot pick up another clone's sessions.
+func (m *Manager) sessionMatchers() []git.SessionMatcher {
+	stored := func(path string) (string, bool) {
+		_, name, ok := layout.Stored(path)
+		return name, ok
+	}
+	return []git.SessionMatcher{m.layout.SessionName, stored}
 }
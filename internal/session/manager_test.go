@@ -0,0 +1,370 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/config"
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+)
+
+func TestConcurrentCreateSession(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	// Each goroutine uses its own manager, and so its own lock file
+	// handle, just like separate ccswitch processes would
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = NewManager(repo).CreateSession(CreateOptions{Description: "Same thing"})
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.IsBranchExists(err), errors.IsWorktreeExists(err):
+		default:
+			t.Errorf("CreateSession() failed unexpectedly: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d concurrent creates succeeded, expected exactly 1", created)
+	}
+
+	// Nothing half-built may be left behind
+	output, err := exec.Command("git", "-C", repo, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		t.Fatalf("git worktree list failed: %v", err)
+	}
+	if n := strings.Count(string(output), "worktree "); n != 2 {
+		t.Errorf("found %d worktrees, expected the main repository and one session:\n%s", n, output)
+	}
+
+	output, err = exec.Command("git", "-C", repo, "for-each-ref", "--format=%(refname:short)", "refs/heads/feature/").Output()
+	if err != nil {
+		t.Fatalf("git for-each-ref failed: %v", err)
+	}
+	if branches := strings.Fields(string(output)); len(branches) != 1 {
+		t.Errorf("found branches %v, expected only feature/same-thing", branches)
+	}
+
+	manager := NewManager(repo)
+	root, _ := layout.Root()
+	entries, err := os.ReadDir(filepath.Join(root, manager.RepoID()))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("worktree directory has %d entries (%v), expected 1", len(entries), err)
+	}
+
+	records, err := manager.store.List()
+	if err != nil || len(records) != 1 {
+		t.Errorf("state has %d sessions (%v), expected 1", len(records), err)
+	}
+}
+
+func TestRemoveSessionRefusesToLoseWork(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	m := NewManager(repo)
+	result, err := m.CreateSession(CreateOptions{Description: "Risky"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	path, branch := result.Session.Path, result.Session.Branch
+
+	runGit(t, path, "commit", "--allow-empty", "-m", "Only here")
+	writeTestFile(t, filepath.Join(path, "notes.txt"), "todo")
+
+	loss, err := m.CheckRemoval(path, branch, true)
+	if err != nil {
+		t.Fatalf("CheckRemoval() failed: %v", err)
+	}
+	if len(loss.Untracked) != 1 || loss.Untracked[0] != "notes.txt" || len(loss.Commits) != 1 {
+		t.Errorf("CheckRemoval() = %+v, expected notes.txt and one commit", loss)
+	}
+
+	err = m.RemoveSession(path, RemoveOptions{Branch: branch, DeleteBranch: true})
+	if !errors.IsUncommittedChanges(err) {
+		t.Fatalf("RemoveSession() error = %v, expected ErrUncommittedChanges", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "notes.txt")); err != nil {
+		t.Fatal("a refused removal must leave the worktree alone")
+	}
+
+	// Once the file is committed, the commits only matter if the branch goes
+	runGit(t, path, "add", "notes.txt")
+	runGit(t, path, "commit", "-m", "Notes")
+	if loss, err := m.CheckRemoval(path, branch, false); err != nil || !loss.Empty() {
+		t.Errorf("CheckRemoval() keeping the branch = %+v, %v; expected nothing lost", loss, err)
+	}
+	runGit(t, repo, "branch", "backup", branch)
+	if loss, err := m.CheckRemoval(path, branch, true); err != nil || !loss.Empty() {
+		t.Errorf("CheckRemoval() with the commits on another branch = %+v, %v; expected nothing lost", loss, err)
+	}
+	runGit(t, repo, "branch", "-D", "backup")
+
+	if err := m.RemoveSession(path, RemoveOptions{Branch: branch, DeleteBranch: true, Force: true}); err != nil {
+		t.Fatalf("RemoveSession() with Force failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("the worktree should be gone")
+	}
+	if err := exec.Command("git", "-C", repo, "rev-parse", "--verify", "refs/heads/"+branch).Run(); err == nil {
+		t.Error("the branch should be gone")
+	}
+}
+
+func TestIsProtected(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	writeTestFile(t, filepath.Join(repo, ".ccswitch.yaml"), "git:\n  protected_branches:\n    - release/*\n")
+
+	m := NewManager(repo)
+	if got := m.DefaultBranch(); got != "main" {
+		t.Fatalf("DefaultBranch() = %q, expected main", got)
+	}
+	for branch, want := range map[string]bool{
+		"main":            true,
+		"release/1.0":     true,
+		"release/1.0/hot": false,
+		"feature/release": false,
+		"":                false,
+	} {
+		if got := m.IsProtected(branch); got != want {
+			t.Errorf("IsProtected(%q) = %v, expected %v", branch, got, want)
+		}
+	}
+
+	if err := m.RemoveSession(repo, RemoveOptions{Branch: "main", DeleteBranch: true, Force: true}); err == nil {
+		t.Error("RemoveSession() should refuse to delete the default branch")
+	}
+}
+
+func TestRepoHooksNeedTrust(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	writeTestFile(t, filepath.Join(repo, ".ccswitch.yaml"), "hooks:\n  pre_create:\n    - touch created-by-hook\n")
+
+	if _, err := NewManager(repo).CreateSession(CreateOptions{Description: "Untrusted"}); err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "created-by-hook")); !os.IsNotExist(err) {
+		t.Fatal("a hook from an untrusted .ccswitch.yaml ran")
+	}
+
+	if err := config.Trust(repo, []byte("hooks:\n  pre_create:\n    - touch created-by-hook\n")); err != nil {
+		t.Fatalf("Trust() failed: %v", err)
+	}
+	if _, err := NewManager(repo).CreateSession(CreateOptions{Description: "Trusted"}); err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "created-by-hook")); err != nil {
+		t.Errorf("the hook from a trusted .ccswitch.yaml did not run: %v", err)
+	}
+}
+
+func TestCreateSessionDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	writeTestFile(t, filepath.Join(repo, ".ccswitch.yaml"), "hooks:\n  pre_create:\n    - touch created-by-hook\n")
+	if err := config.Trust(repo, []byte("hooks:\n  pre_create:\n    - touch created-by-hook\n")); err != nil {
+		t.Fatalf("Trust() failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	m := NewManager(repo)
+	m.SetDryRun(&out)
+	result, err := m.CreateSession(CreateOptions{Description: "Dry run"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+
+	if _, err := os.Stat(result.Session.Path); !os.IsNotExist(err) {
+		t.Errorf("a dry run created the worktree %s", result.Session.Path)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "created-by-hook")); !os.IsNotExist(err) {
+		t.Error("a dry run ran the pre_create hook")
+	}
+	if NewManager(repo).branchManager.Exists(result.Session.Branch) {
+		t.Errorf("a dry run created the branch %s", result.Session.Branch)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".ccswitch", "state")); !os.IsNotExist(err) {
+		t.Error("a dry run recorded the session")
+	}
+
+	for _, want := range []string{
+		"[dry-run] mkdir " + filepath.Dir(result.Session.Path),
+		"[dry-run] run pre_create hook in " + repo + ": touch created-by-hook",
+		"[dry-run] git -C " + repo + " branch --no-track " + result.Session.Branch,
+		"[dry-run] git -C " + repo + " worktree add " + result.Session.Path + " " + result.Session.Branch,
+		"[dry-run] record session " + result.Session.Name,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRenameSessionKeepsCheckedOutBranch(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	runGit(t, repo, "branch", "hotfix/login")
+
+	m := NewManager(repo)
+	if _, err := m.CheckoutSession("hotfix/login"); err != nil {
+		t.Fatalf("CheckoutSession() failed: %v", err)
+	}
+	renamed, err := m.RenameSession("hotfix-login", "Login timeout")
+	if err != nil {
+		t.Fatalf("RenameSession() failed: %v", err)
+	}
+	if renamed.Branch != "hotfix/login" || !m.branchManager.Exists("hotfix/login") {
+		t.Errorf("renamed branch = %q, expected hotfix/login to keep its name", renamed.Branch)
+	}
+	if m.branchManager.Exists("feature/login-timeout") {
+		t.Error("RenameSession() created a branch for a session checked out under its own name")
+	}
+	if renamed.Path != m.GetSessionPath("login-timeout") {
+		t.Errorf("renamed path = %q, expected the worktree to move", renamed.Path)
+	}
+
+	// A branch named after its session follows it
+	if _, err := m.CreateSession(CreateOptions{Description: "Old name"}); err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	renamed, err = m.RenameSession("old-name", "New name")
+	if err != nil {
+		t.Fatalf("RenameSession() failed: %v", err)
+	}
+	if renamed.Branch != "feature/new-name" {
+		t.Errorf("renamed branch = %q, expected feature/new-name", renamed.Branch)
+	}
+}
+
+func TestRenameSessionFollowsRecordedBranchName(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	m := NewManager(repo)
+
+	// A branch given with --branch is the user's, even if it looks like ours
+	if _, err := m.CreateSession(CreateOptions{Description: "Login", BranchName: "feature/login"}); err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	renamed, err := m.RenameSession("login", "Login timeout")
+	if err != nil {
+		t.Fatalf("RenameSession() failed: %v", err)
+	}
+	if renamed.Branch != "feature/login" {
+		t.Errorf("renamed branch = %q, expected the --branch name to be kept", renamed.Branch)
+	}
+
+	// A branch ccswitch named follows the session after the prefix changed
+	if _, err := m.CreateSession(CreateOptions{Description: "Old name"}); err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	m.config.Branch.Prefix = "feat/"
+	renamed, err = m.RenameSession("old-name", "New name")
+	if err != nil {
+		t.Fatalf("RenameSession() failed: %v", err)
+	}
+	if renamed.Branch != "feat/new-name" || m.branchManager.Exists("feature/old-name") {
+		t.Errorf("renamed branch = %q, expected feature/old-name to become feat/new-name", renamed.Branch)
+	}
+
+	// And keeps doing so on the next rename
+	renamed, err = m.RenameSession("new-name", "Newer name")
+	if err != nil {
+		t.Fatalf("RenameSession() failed: %v", err)
+	}
+	if renamed.Branch != "feat/newer-name" {
+		t.Errorf("renamed branch = %q, expected feat/newer-name", renamed.Branch)
+	}
+}
+
+func TestRemoveSessionClassifiesGitFailures(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	tests := []struct {
+		name   string
+		stderr string
+		is     func(error) bool
+		code   int
+	}{
+		{"not merged", "error: The branch 'feature/fix-bug' is not fully merged.\nIf you are sure you want to delete it, run 'git branch -D feature/fix-bug'.", errors.IsBranchNotMerged, errors.ExitUnsavedWork},
+		{"checked out", "error: Cannot delete branch 'feature/fix-bug' checked out at '/elsewhere'", errors.IsBranchCheckedOut, errors.ExitBranchInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := git.NewFakeRunner()
+			m := newFakeManager(fake)
+			path := m.GetSessionPath("fix-bug")
+			fake.On("worktree remove "+path+" --force", "").
+				Fail("branch -D feature/fix-bug", 1, tt.stderr)
+
+			err := m.RemoveSession(path, RemoveOptions{Branch: "feature/fix-bug", DeleteBranch: true, Force: true})
+			if !tt.is(err) {
+				t.Fatalf("RemoveSession() = %v, expected git's failure to be classified", err)
+			}
+			if errors.ExitCode(err) != tt.code {
+				t.Errorf("ExitCode() = %d, expected %d", errors.ExitCode(err), tt.code)
+			}
+			if !fake.Ran("worktree remove " + path + " --force") {
+				t.Error("the worktree was not removed before the branch")
+			}
+		})
+	}
+}
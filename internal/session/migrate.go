@@ -0,0 +1,271 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/state"
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// Migration describes a session worktree moved into this repository's
+// storage directory
+type Migration struct {
+	Session string
+	From    string
+	To      string
+}
+
+// LegacySessions returns the sessions still stored in another directory of
+// ~/.ccswitch/worktrees than the one named by the repository's ID: the
+// legacy <repo>/ directory, which is shared by every repository with the
+// same name, or the ID the repository had before it was moved
+func (m *Manager) LegacySessions(sessions []git.SessionInfo) []git.SessionInfo {
+	if m.repoID == m.repoName {
+		return nil
+	}
+
+	var found []git.SessionInfo
+	for _, s := range sessions {
+		if dir, _, ok := layout.Stored(s.Path); ok && dir != m.repoID {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// MigrateSessions moves the sessions LegacySessions finds into the
+// directory named by the repository's ID, keeping their branches, metadata
+// and trash
+func (m *Manager) MigrateSessions() ([]Migration, error) {
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []Migration
+	for _, s := range m.LegacySessions(sessions) {
+		mig, err := m.migrate(s)
+		if err != nil {
+			return migrated, err
+		}
+		migrated = append(migrated, mig)
+	}
+	return migrated, nil
+}
+
+// MigrateSession moves the session at path, which LegacySessions must
+// report, like MigrateSessions does
+func (m *Manager) MigrateSession(path string) (*Migration, error) {
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range m.LegacySessions(sessions) {
+		if canonicalPath(s.Path) == canonicalPath(path) {
+			mig, err := m.migrate(s)
+			if err != nil {
+				return nil, err
+			}
+			return &mig, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no session of %s is stored at %s", errors.ErrSessionNotFound, m.mainRepoPath, path)
+}
+
+// migrate moves one session into the directory named by the repository's ID
+func (m *Manager) migrate(s git.SessionInfo) (Migration, error) {
+	centralized, err := layout.FromTemplate(layout.CentralizedTemplate, m.repo(), m.config.Worktree.RelativePath)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	newPath := centralized.SessionPath(s.Name)
+	if _, err := os.Stat(newPath); err == nil {
+		return Migration{}, fmt.Errorf("%w: %s", errors.ErrWorktreeExists, newPath)
+	}
+	dir := filepath.Dir(newPath)
+	if err := m.change("mkdir -p "+dir, func() error { return os.MkdirAll(dir, 0755) }); err != nil {
+		return Migration{}, errors.Wrap(err, "failed to create worktree directory")
+	}
+	if err := m.worktreeManager.Move(s.Path, newPath); err != nil {
+		return Migration{}, fmt.Errorf("failed to move session %s: %w", s.Name, err)
+	}
+	mig := Migration{Session: s.Name, From: s.Path, To: newPath}
+
+	if err := m.updateRecordPath(s, newPath); err != nil {
+		return mig, errors.Wrap(err, "failed to record session metadata")
+	}
+
+	// Sessions removed while the repository had its old ID went to that
+	// ID's trash. The legacy directory is shared by name, and predates the
+	// trash anyway.
+	oldDir, _, _ := layout.Stored(s.Path)
+	if oldDir != m.repoName {
+		if err := m.adoptTrash(oldDir, centralized); err != nil {
+			return mig, errors.Wrap(err, "failed to move the trash")
+		}
+	}
+
+	// Drop the directory left behind once no session, of this or another
+	// clone, uses it
+	left := filepath.Dir(s.Path)
+	if entries, err := os.ReadDir(left); err == nil && len(entries) == 0 {
+		_ = m.change("rmdir "+left, func() error { return os.Remove(left) })
+	}
+
+	return mig, nil
+}
+
+// adoptTrash moves the trash kept under the repository's old ID into its
+// own, pointing entries for sessions stored under the old ID at where
+// they'd be restored now
+func (m *Manager) adoptTrash(oldID string, centralized *layout.Layout) error {
+	dir, err := trashDir()
+	if err != nil {
+		return err
+	}
+	oldRoot := filepath.Join(dir, oldID)
+	entries, err := os.ReadDir(oldRoot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	root, err := m.trashPath()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		src := filepath.Join(oldRoot, e.Name())
+		dst := filepath.Join(root, e.Name())
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+		err := m.change("mv "+src+" "+dst, func() error {
+			if err := os.MkdirAll(root, 0700); err != nil {
+				return err
+			}
+			if err := os.Rename(src, dst); err != nil {
+				return err
+			}
+			return repointTrashEntry(filepath.Join(dst, trashEntryFile), oldID, centralized)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_ = m.change("rmdir "+oldRoot, func() error { return os.Remove(oldRoot) })
+	return nil
+}
+
+// repointTrashEntry moves the session of a trash entry stored under oldID
+// to the path the layout gives it
+func repointTrashEntry(path, oldID string, centralized *layout.Layout) error {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	if dir, _, ok := layout.Stored(entry.Session.Path); !ok || dir != oldID {
+		return nil
+	}
+	entry.Session.Path = centralized.SessionPath(entry.Session.Name)
+	data, err = json.MarshalIndent(&entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// updateRecordPath points a session's metadata at its new worktree path,
+// moving it into this repository's state file from wherever it was kept
+func (m *Manager) updateRecordPath(s git.SessionInfo, newPath string) error {
+	if m.store == nil {
+		return nil
+	}
+	store, rec := m.findRecord(s, make(map[string]*state.File))
+	moved := state.Session{Name: s.Name}
+	if rec != nil {
+		moved = *rec
+	}
+	moved.Branch = s.Branch
+	moved.Path = newPath
+	return m.change("record session "+moved.Name+" at "+newPath, func() error {
+		if err := m.store.Put(moved); err != nil {
+			return err
+		}
+		if store != nil && store != m.store {
+			return forgetRecord(store, moved.Name)
+		}
+		return nil
+	})
+}
+
+// forgetRecord deletes a record that moved to another state file, and the
+// file it was in once that is empty
+func forgetRecord(store *state.Store, name string) error {
+	if err := store.Delete(name); err != nil {
+		return err
+	}
+	if left, err := store.List(); err == nil && len(left) == 0 {
+		return store.Remove()
+	}
+	return nil
+}
+
+// adoptRecords moves the metadata of this repository's sessions into its
+// own state file from the ones kept under an earlier ID or, by older
+// versions, under the repository's name. Those may be shared with other
+// clones of the same name, so only records whose worktree belongs to this
+// repository are taken, and a file is removed once it is empty. It rewrites
+// state, so it runs with the lock held, never while just listing.
+func (m *Manager) adoptRecords() {
+	// Moving records is housekeeping; a dry run leaves it to the next real run
+	if m.IsDryRun() || m.store == nil {
+		return
+	}
+	worktrees, err := m.worktreeManager.List()
+	if err != nil {
+		return
+	}
+
+	loaded := make(map[string]*state.File)
+	for _, s := range git.GetSessionsFromWorktrees(worktrees, m.repoName, m.sessionMatchers()...) {
+		store, rec := m.findRecord(s, loaded)
+		if store == nil || store == m.store {
+			continue
+		}
+		if err := m.store.Put(*rec); err != nil {
+			return
+		}
+		if err := forgetRecord(store, rec.Name); err != nil {
+			return
+		}
+		// Both files changed, so read them again
+		delete(loaded, store.Path())
+		delete(loaded, m.store.Path())
+	}
+}
@@ -0,0 +1,231 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/lock"
+	"github.com/ksred/ccswitch/internal/state"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func initTestRepo(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	runGit(t, dir, "init", "-b", "main")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "commit", "--allow-empty", "-m", "Initial commit")
+}
+
+func findByName(sessions []git.SessionInfo, name string) *git.SessionInfo {
+	for i := range sessions {
+		if sessions[i].Name == name {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
+
+func TestMigrateLegacySessions(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// Two clones that share a directory name
+	work := filepath.Join(tempDir, "work", "api")
+	oss := filepath.Join(tempDir, "oss", "api")
+	initTestRepo(t, work)
+	initTestRepo(t, oss)
+
+	// A session created by an older ccswitch, before worktrees and state
+	// were namespaced by repository ID
+	root, _ := layout.Root()
+	legacyPath := filepath.Join(root, "api", "fix-bug")
+	runGit(t, work, "worktree", "add", "-b", "feature/fix-bug", legacyPath)
+
+	legacyStore, _ := state.NewStore("api")
+	if err := legacyStore.Put(state.Session{Name: "fix-bug", Branch: "feature/fix-bug", Path: legacyPath, Description: "Fix bug"}); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	manager := NewManager(work)
+	sessions, err := manager.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if s := findByName(sessions, "fix-bug"); s == nil || s.Description != "Fix bug" {
+		t.Fatalf("ListSessions() = %+v, expected the legacy session with its description", sessions)
+	}
+	if !legacyStore.Exists() {
+		t.Error("listing sessions should leave the legacy state file alone")
+	}
+	if len(manager.LegacySessions(sessions)) != 1 {
+		t.Errorf("LegacySessions() should report the session as needing migration")
+	}
+
+	// The other clone must not see the session
+	otherSessions, err := NewManager(oss).ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if findByName(otherSessions, "fix-bug") != nil {
+		t.Errorf("ListSessions() for another clone = %+v, expected none", otherSessions)
+	}
+
+	migrated, err := manager.MigrateSessions()
+	if err != nil {
+		t.Fatalf("MigrateSessions() failed: %v", err)
+	}
+	expectedPath := filepath.Join(root, manager.RepoID(), "fix-bug")
+	if len(migrated) != 1 || migrated[0].To != expectedPath {
+		t.Fatalf("MigrateSessions() = %+v, expected a move to %s", migrated, expectedPath)
+	}
+	if _, err := os.Stat(filepath.Join(root, "api")); !os.IsNotExist(err) {
+		t.Error("empty legacy directory should be removed")
+	}
+	if legacyStore.Exists() {
+		t.Error("legacy state file should be removed once its sessions are adopted")
+	}
+
+	sessions, err = manager.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if s := findByName(sessions, "fix-bug"); s == nil || s.Path != expectedPath || s.Description != "Fix bug" {
+		t.Errorf("ListSessions() after migration = %+v", sessions)
+	}
+	if len(manager.LegacySessions(sessions)) != 0 {
+		t.Error("LegacySessions() should be empty after migration")
+	}
+}
+
+func TestSessionsOfAMovedRepository(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// A session created before repositories recorded their ID, so moving
+	// the repository changes it
+	repo := filepath.Join(tempDir, "src", "api")
+	initTestRepo(t, repo)
+	oldID, err := git.Default().GetRepoID(repo)
+	if err != nil {
+		t.Fatalf("GetRepoID() failed: %v", err)
+	}
+	root, _ := layout.Root()
+	oldPath := filepath.Join(root, oldID, "fix-bug")
+	runGit(t, repo, "worktree", "add", "-b", "feature/fix-bug", oldPath)
+	oldStore, _ := state.NewStore(oldID)
+	if err := oldStore.Put(state.Session{Name: "fix-bug", Branch: "feature/fix-bug", Path: oldPath, Description: "Fix bug"}); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	moved := filepath.Join(tempDir, "moved-api")
+	if err := os.Rename(repo, moved); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, moved, "worktree", "repair", oldPath)
+
+	manager := NewManager(moved)
+	if manager.RepoID() == oldID {
+		t.Fatalf("RepoID() = %q, expected moving the repository to change an unrecorded ID", oldID)
+	}
+	sessions, err := manager.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if s := findByName(sessions, "fix-bug"); s == nil || s.Description != "Fix bug" {
+		t.Fatalf("ListSessions() = %+v, expected the session stored under the old ID with its description", sessions)
+	}
+	if len(manager.LegacySessions(sessions)) != 1 {
+		t.Errorf("LegacySessions() should report the session as needing migration")
+	}
+
+	migrated, err := manager.MigrateSessions()
+	if err != nil {
+		t.Fatalf("MigrateSessions() failed: %v", err)
+	}
+	newPath := filepath.Join(root, manager.RepoID(), "fix-bug")
+	if len(migrated) != 1 || migrated[0].To != newPath {
+		t.Fatalf("MigrateSessions() = %+v, expected a move to %s", migrated, newPath)
+	}
+	if oldStore.Exists() {
+		t.Error("the old ID's state file should be removed once its records moved")
+	}
+	sessions, err = manager.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	if s := findByName(sessions, "fix-bug"); s == nil || s.Path != newPath || s.Description != "Fix bug" {
+		t.Errorf("ListSessions() after migration = %+v", sessions)
+	}
+
+	// Now that the ID is recorded, moving the repository again keeps it
+	again := filepath.Join(tempDir, "api-again")
+	if err := os.Rename(moved, again); err != nil {
+		t.Fatal(err)
+	}
+	if id := NewManager(again).RepoID(); id != manager.RepoID() {
+		t.Errorf("RepoID() after moving again = %q, expected the recorded %q", id, manager.RepoID())
+	}
+}
+
+func TestListSessionsDoesNotWaitForTheLock(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	root, _ := layout.Root()
+	legacyPath := filepath.Join(root, "api", "fix-bug")
+	runGit(t, repo, "worktree", "add", "-b", "feature/fix-bug", legacyPath)
+	legacyStore, _ := state.NewStore("api")
+	if err := legacyStore.Put(state.Session{Name: "fix-bug", Branch: "feature/fix-bug", Path: legacyPath, Description: "Fix bug"}); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	// Another run, say a sync, holds the lock
+	manager := NewManager(repo)
+	held, err := lock.Acquire(manager.RepoID(), time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() failed: %v", err)
+	}
+	defer held.Release()
+
+	done := make(chan []git.SessionInfo, 1)
+	go func() {
+		sessions, _ := manager.ListSessions()
+		done <- sessions
+	}()
+	select {
+	case sessions := <-done:
+		if s := findByName(sessions, "fix-bug"); s == nil || s.Description != "Fix bug" {
+			t.Errorf("ListSessions() = %+v, expected the legacy session with its description", sessions)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListSessions() waited for the lock")
+	}
+}
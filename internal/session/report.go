@@ -0,0 +1,147 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/github"
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// Report gathers what the status dashboard shows about a session
+type Report struct {
+	Session git.SessionInfo
+
+	// DefaultBranch is the ref Ahead and Behind are counted against; empty
+	// if the default branch could not be found
+	DefaultBranch string
+	Ahead         int
+	Behind        int
+	// SquashMerged is set when the session's commits landed on the default
+	// branch as one squashed commit
+	SquashMerged bool
+
+	// PR is the session's pull request, nil if it has none or the GitHub
+	// CLI is unavailable
+	PR *github.PullRequest
+
+	// DiskUsage is the size of the worktree in bytes, -1 if unknown
+	DiskUsage int64
+
+	// landed is set for a session without a recorded base commit whose
+	// branch is an ancestor of the default branch, which has moved past it
+	landed bool
+}
+
+// ReportOptions turns on the slower parts of a report
+type ReportOptions struct {
+	PullRequests bool
+	DiskUsage    bool
+}
+
+// LastActivity returns when the session was last worked on: its creation,
+// last commit or the last time it was switched to, whichever is latest
+func (r *Report) LastActivity() time.Time {
+	last := r.Session.CreatedAt
+	if r.Session.LastSwitchedAt.After(last) {
+		last = r.Session.LastSwitchedAt
+	}
+	if s := r.Session.Status; s != nil && s.LastCommitTime.After(last) {
+		last = s.LastCommitTime
+	}
+	return last
+}
+
+// Stale reports whether the session has gone without activity for at least
+// d. A session whose last activity is unknown is never stale.
+func (r *Report) Stale(d time.Duration) bool {
+	last := r.LastActivity()
+	return !last.IsZero() && time.Since(last) >= d
+}
+
+// Merged reports whether the session's work has landed on the default
+// branch: its pull request was merged, it was squash-merged, or it has
+// commits of its own and all of them are on the default branch. Without a
+// recorded base commit, as for sessions checked out from an existing
+// branch, the branch counts once the default branch has moved past it.
+func (r *Report) Merged() bool {
+	if (r.PR != nil && r.PR.State == github.StateMerged) || r.SquashMerged {
+		return true
+	}
+	status := r.Session.Status
+	if r.DefaultBranch == "" || status == nil || status.Head == "" || r.Ahead > 0 {
+		return false
+	}
+	if r.Session.BaseCommit == "" {
+		return r.landed
+	}
+	// A branch still at the commit it started from has nothing to merge
+	return status.Head != r.Session.BaseCommit
+}
+
+// Reports gathers a report for each session, reading worktrees concurrently
+func (m *Manager) Reports(sessions []git.SessionInfo, opts ReportOptions) []Report {
+	reports := make([]Report, len(sessions))
+	for i, s := range sessions {
+		reports[i] = Report{Session: s, DiskUsage: -1}
+	}
+
+	// One gh call for the whole repository, alongside the git work
+	var prs map[string]github.PullRequest
+	var prWait sync.WaitGroup
+	if opts.PullRequests && github.Available() {
+		prWait.Add(1)
+		go func() {
+			defer prWait.Done()
+			prs, _ = github.PullRequestsByBranch(m.mainRepoPath)
+		}()
+	}
+
+	defaultRef := m.defaultBranchRef()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, statusConcurrency)
+	for i := range reports {
+		wg.Add(1)
+		go func(r *Report) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			base := r.Session.BaseBranch
+			if base == "" {
+				base = defaultRef
+			}
+			if status, err := m.git.GetStatus(r.Session.Path, base); err == nil {
+				r.Session.Status = status
+			}
+			if defaultRef != "" {
+				if ahead, behind, err := m.git.AheadBehind(r.Session.Path, defaultRef); err == nil {
+					r.DefaultBranch, r.Ahead, r.Behind = defaultRef, ahead, behind
+				}
+				if r.Ahead > 0 {
+					r.SquashMerged, _ = m.git.SquashMerged(r.Session.Path, defaultRef)
+				}
+				if status := r.Session.Status; r.Session.BaseCommit == "" && r.Behind > 0 && status != nil && status.Head != "" {
+					r.landed = m.git.IsAncestor(r.Session.Path, status.Head, defaultRef)
+				}
+			}
+			if opts.DiskUsage {
+				if size, err := utils.DirSize(r.Session.Path); err == nil {
+					r.DiskUsage = size
+				}
+			}
+		}(&reports[i])
+	}
+	wg.Wait()
+	prWait.Wait()
+
+	for i := range reports {
+		if pr, ok := prs[reports[i].Session.Branch]; ok {
+			reports[i].PR = &pr
+		}
+	}
+
+	return reports
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/git"
+)
+
+func TestReports(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	m := NewManager(repo)
+	var paths []string
+	for _, description := range []string{"Shipped", "Untouched", "In progress", "Squashed"} {
+		result, err := m.CreateSession(CreateOptions{Description: description})
+		if err != nil {
+			t.Fatalf("CreateSession() failed: %v", err)
+		}
+		paths = append(paths, result.Session.Path)
+	}
+
+	// shipped has landed on main, in-progress has unmerged and uncommitted work
+	runGit(t, paths[0], "commit", "--allow-empty", "-m", "Ship it")
+	runGit(t, repo, "merge", "--ff-only", "feature/shipped")
+	runGit(t, paths[2], "commit", "--allow-empty", "-m", "Half done")
+	writeTestFile(t, filepath.Join(paths[2], "notes.txt"), "todo")
+
+	// squashed landed as a single squash commit, so it is not an ancestor
+	writeTestFile(t, filepath.Join(paths[3], "feature.txt"), "done")
+	runGit(t, paths[3], "add", "feature.txt")
+	runGit(t, paths[3], "commit", "-m", "Add feature")
+	runGit(t, repo, "merge", "--squash", "feature/squashed")
+	runGit(t, repo, "commit", "-m", "Add feature (#12)")
+
+	sessions, err := m.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	reports := m.Reports(sessions, ReportOptions{DiskUsage: true})
+
+	byName := map[string]Report{}
+	for _, r := range reports {
+		byName[r.Session.Name] = r
+	}
+
+	shipped := byName["shipped"]
+	if !shipped.Merged() {
+		t.Errorf("shipped should be merged: %+v", shipped)
+	}
+
+	squashed := byName["squashed"]
+	if !squashed.Merged() || !squashed.SquashMerged || squashed.Ahead != 1 {
+		t.Errorf("squashed should be squash-merged: %+v", squashed)
+	}
+
+	untouched := byName["untouched"]
+	if untouched.Merged() {
+		t.Error("a session without commits of its own should not count as merged")
+	}
+	if untouched.Behind != 2 || untouched.DefaultBranch != "main" {
+		t.Errorf("untouched is %d behind %q, expected 2 behind main", untouched.Behind, untouched.DefaultBranch)
+	}
+
+	inProgress := byName["in-progress"]
+	if inProgress.Merged() || inProgress.Ahead != 1 {
+		t.Errorf("in-progress: merged = %v, ahead = %d; expected unmerged and 1 ahead", inProgress.Merged(), inProgress.Ahead)
+	}
+	if inProgress.Session.Status == nil || inProgress.Session.Status.Untracked != 1 {
+		t.Errorf("in-progress status = %+v, expected 1 untracked file", inProgress.Session.Status)
+	}
+	if inProgress.DiskUsage <= 0 {
+		t.Errorf("DiskUsage = %d, expected the size of the worktree", inProgress.DiskUsage)
+	}
+	if inProgress.LastActivity().IsZero() {
+		t.Error("LastActivity() should fall back to the last commit")
+	}
+}
+
+func TestReportsWithoutBaseCommit(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	runGit(t, repo, "branch", "hotfix/login")
+
+	// Checked-out sessions have no base commit to compare against
+	m := NewManager(repo)
+	if _, err := m.CheckoutSession("hotfix/login"); err != nil {
+		t.Fatalf("CheckoutSession() failed: %v", err)
+	}
+	hotfix := m.GetSessionPath("hotfix-login")
+	runGit(t, hotfix, "commit", "--allow-empty", "-m", "Fix login")
+	runGit(t, repo, "merge", "--no-ff", "-m", "Merge hotfix", "hotfix/login")
+
+	// A branch where main is now has nothing merged yet
+	runGit(t, repo, "branch", "fresh")
+	if _, err := m.CheckoutSession("fresh"); err != nil {
+		t.Fatalf("CheckoutSession() failed: %v", err)
+	}
+
+	sessions, err := m.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions() failed: %v", err)
+	}
+	byName := map[string]Report{}
+	for _, r := range m.Reports(sessions, ReportOptions{}) {
+		if r.Session.BaseCommit != "" {
+			t.Fatalf("%s has a base commit, which this test needs missing", r.Session.Name)
+		}
+		byName[r.Session.Name] = r
+	}
+
+	if r := byName["hotfix-login"]; !r.Merged() {
+		t.Errorf("hotfix-login should count as merged once main moved past it: %+v", r)
+	}
+	if r := byName["fresh"]; r.Merged() {
+		t.Errorf("fresh should not count as merged: %+v", r)
+	}
+}
+
+func TestStale(t *testing.T) {
+	old := Report{Session: git.SessionInfo{CreatedAt: time.Now().Add(-48 * time.Hour)}}
+	if !old.Stale(24 * time.Hour) {
+		t.Error("a session untouched for two days should be stale after one")
+	}
+	if old.Stale(72 * time.Hour) {
+		t.Error("a session untouched for two days should not be stale after three")
+	}
+
+	var unknown Report
+	if unknown.Stale(time.Hour) {
+		t.Error("a session whose last activity is unknown should never be stale")
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"github.com/ksred/ccswitch/internal/git"
+)
+
+// Outcomes of syncing a session
+const (
+	SyncUpdated  = "updated"
+	SyncUpToDate = "up_to_date"
+	SyncSkipped  = "skipped"
+	SyncConflict = "conflict"
+	SyncFailed   = "failed"
+)
+
+// SyncOptions controls SyncSession
+type SyncOptions struct {
+	// Merge merges the base into the session instead of rebasing onto it
+	Merge bool
+	// LeaveConflicts leaves a conflicted rebase or merge in progress for the
+	// user to finish, instead of aborting it
+	LeaveConflicts bool
+}
+
+// SyncResult is what syncing a session did
+type SyncResult struct {
+	Session git.SessionInfo `json:"session" yaml:"session"`
+	Outcome string          `json:"outcome" yaml:"outcome"`
+	// Behind counts the base's commits the session lacked
+	Behind int `json:"behind" yaml:"behind"`
+	// Detail explains a skip, conflict or failure
+	Detail    string   `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+// LatestDefaultBranch returns the default branch, or its remote
+// counterpart when the local branch is merely behind it, so sessions can be
+// brought up to date without touching the main worktree. It is empty when
+// neither exists.
+func (m *Manager) LatestDefaultBranch() string {
+	local := m.DefaultBranch()
+	remote := "origin/" + local
+	hasLocal := m.branchManager.Exists(local)
+	hasRemote := m.branchManager.RefExists("refs/remotes/" + remote)
+
+	switch {
+	case hasLocal && hasRemote && m.git.IsAncestor(m.mainRepoPath, local, remote):
+		return remote
+	case hasLocal:
+		return local
+	case hasRemote:
+		return remote
+	default:
+		return ""
+	}
+}
+
+// SyncSession rebases a session's branch onto base, or merges base into
+// it. Sessions with uncommitted changes are skipped; untracked files are
+// left to git, which refuses to overwrite them. A conflict stops the
+// session's rebase or merge, which is aborted unless opts.LeaveConflicts
+// is set.
+func (m *Manager) SyncSession(s git.SessionInfo, base string, opts SyncOptions) SyncResult {
+	r := SyncResult{Session: s}
+	finish := func(outcome, detail string) SyncResult {
+		r.Outcome, r.Detail = outcome, detail
+		return r
+	}
+
+	release, err := m.lock()
+	if err != nil {
+		return finish(SyncFailed, err.Error())
+	}
+	defer release()
+
+	if s.Branch == "" {
+		return finish(SyncSkipped, "not on a branch")
+	}
+	status, err := m.git.GetStatus(s.Path, "")
+	if err != nil {
+		return finish(SyncFailed, err.Error())
+	}
+	if status.Changed > 0 {
+		return finish(SyncSkipped, "uncommitted changes")
+	}
+
+	if _, r.Behind, err = m.git.AheadBehind(s.Path, base); err != nil {
+		return finish(SyncFailed, err.Error())
+	}
+	if r.Behind == 0 {
+		return finish(SyncUpToDate, "")
+	}
+
+	update, abort := m.git.Rebase, m.git.AbortRebase
+	if opts.Merge {
+		update, abort = m.git.Merge, m.git.AbortMerge
+	}
+	if err := update(s.Path, base); err != nil {
+		conflicts, _ := m.git.ConflictedFiles(s.Path)
+		if len(conflicts) == 0 {
+			// Whatever stopped git, don't leave the session half updated
+			_ = abort(s.Path)
+			return finish(SyncFailed, err.Error())
+		}
+		r.Conflicts = conflicts
+		if opts.LeaveConflicts {
+			return finish(SyncConflict, "left for you to resolve")
+		}
+		if err := abort(s.Path); err != nil {
+			return finish(SyncFailed, err.Error())
+		}
+		return finish(SyncConflict, "aborted, nothing changed")
+	}
+
+	return finish(SyncUpdated, "")
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/git"
+)
+
+func TestSyncSession(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	writeTestFile(t, filepath.Join(repo, "app.txt"), "v1\n")
+	runGit(t, repo, "add", "app.txt")
+	runGit(t, repo, "commit", "-m", "Add app")
+
+	m := NewManager(repo)
+	sessions := map[string]git.SessionInfo{}
+	for _, description := range []string{"Clean", "Dirty", "Clash", "Merged in"} {
+		result, err := m.CreateSession(CreateOptions{Description: description})
+		if err != nil {
+			t.Fatalf("CreateSession() failed: %v", err)
+		}
+		sessions[result.Session.Name] = result.Session
+	}
+
+	for _, name := range []string{"clean", "merged-in"} {
+		writeTestFile(t, filepath.Join(sessions[name].Path, name+".txt"), name)
+		runGit(t, sessions[name].Path, "add", ".")
+		runGit(t, sessions[name].Path, "commit", "-m", "Work on "+name)
+	}
+	writeTestFile(t, filepath.Join(sessions["dirty"].Path, "app.txt"), "v1\nunsaved\n")
+	writeTestFile(t, filepath.Join(sessions["clash"].Path, "app.txt"), "mine\n")
+	runGit(t, sessions["clash"].Path, "commit", "-am", "Change app")
+
+	// main moves on
+	writeTestFile(t, filepath.Join(repo, "app.txt"), "v2\n")
+	runGit(t, repo, "commit", "-am", "Update app")
+
+	base := m.LatestDefaultBranch()
+	if base != "main" {
+		t.Fatalf("LatestDefaultBranch() = %q, expected main", base)
+	}
+
+	tests := []struct {
+		name    string
+		opts    SyncOptions
+		outcome string
+	}{
+		{"clean", SyncOptions{}, SyncUpdated},
+		{"merged-in", SyncOptions{Merge: true}, SyncUpdated},
+		{"dirty", SyncOptions{}, SyncSkipped},
+		{"clash", SyncOptions{}, SyncConflict},
+	}
+	for _, tt := range tests {
+		s := sessions[tt.name]
+		before, _ := git.NewBranchManager(git.Default(), s.Path).ResolveCommit("HEAD")
+		r := m.SyncSession(s, base, tt.opts)
+		if r.Outcome != tt.outcome {
+			t.Errorf("SyncSession(%s) = %s (%s), expected %s", tt.name, r.Outcome, r.Detail, tt.outcome)
+			continue
+		}
+		after, _ := git.NewBranchManager(git.Default(), s.Path).ResolveCommit("HEAD")
+		if moved := before != after; moved != (tt.outcome == SyncUpdated) {
+			t.Errorf("SyncSession(%s) %s: HEAD went from %s to %s", tt.name, tt.outcome, before, after)
+		}
+		if tt.outcome == SyncUpdated && !git.Default().IsAncestor(s.Path, base, "HEAD") {
+			t.Errorf("%s does not contain %s after syncing", tt.name, base)
+		}
+	}
+
+	// An aborted conflict leaves the session as it was
+	if files, _ := git.Default().ConflictedFiles(sessions["clash"].Path); len(files) != 0 {
+		t.Errorf("clash still has conflicts: %v", files)
+	}
+	r := m.SyncSession(sessions["clash"], base, SyncOptions{LeaveConflicts: true})
+	if r.Outcome != SyncConflict || len(r.Conflicts) != 1 || r.Conflicts[0] != "app.txt" {
+		t.Errorf("SyncSession(clash) leaving conflicts = %+v, expected a conflict in app.txt", r)
+	}
+	if r := m.SyncSession(sessions["clean"], base, SyncOptions{}); r.Outcome != SyncUpToDate {
+		t.Errorf("syncing clean again = %s, expected up to date", r.Outcome)
+	}
+}
+
+func TestLatestDefaultBranchWithFakeGit(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	fake := git.NewFakeRunner().
+		On("rev-parse --git-common-dir", "/repos/api/.git\n").
+		On("symbolic-ref --quiet --short refs/remotes/origin/HEAD", "origin/main\n").
+		On("rev-parse --verify refs/heads/main", "1111111\n").
+		On("rev-parse --verify refs/remotes/origin/main", "2222222\n")
+	m := NewManagerWithClient(git.NewClient(context.Background(), fake), "/repos/api")
+
+	// The local branch has diverged, so it is what sessions sync with
+	if got := m.LatestDefaultBranch(); got != "main" {
+		t.Errorf("LatestDefaultBranch() = %q, expected main", got)
+	}
+
+	// Once it is merely behind, origin's is newer
+	fake.On("merge-base --is-ancestor main origin/main", "")
+	if got := m.LatestDefaultBranch(); got != "origin/main" {
+		t.Errorf("LatestDefaultBranch() = %q, expected origin/main", got)
+	}
+
+	for _, c := range fake.Calls() {
+		if c.Args[0] == "merge-base" && c.Dir != "/repos/api" {
+			t.Errorf("%s ran outside the main repository", c)
+		}
+	}
+}
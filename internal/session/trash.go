@@ -0,0 +1,506 @@
+package session
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/hooks"
+	"github.com/ksred/ccswitch/internal/state"
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// TrashRefPrefix is where the commits of removed sessions are kept while
+// they are in the trash, so git doesn't garbage collect them
+const TrashRefPrefix = "refs/ccswitch/trash/"
+
+// Files of a trash entry
+const (
+	trashEntryFile    = "entry.json"
+	stagedPatchFile   = "staged.patch"
+	unstagedPatchFile = "unstaged.patch"
+	untrackedFile     = "untracked.tar"
+)
+
+// TrashEntry is a removed session, saved so it can be rebuilt as it was
+type TrashEntry struct {
+	ID      string        `json:"id"`
+	Session state.Session `json:"session"`
+	// Head is the commit the worktree had checked out
+	Head string `json:"head"`
+	// BranchDeleted is set when the session's branch was deleted with it
+	BranchDeleted bool `json:"branch_deleted"`
+	// Remote and Merge are the deleted branch's upstream tracking
+	Remote string `json:"remote,omitempty"`
+	Merge  string `json:"merge,omitempty"`
+	// Changed and Untracked count the files saved with the session
+	Changed   int       `json:"changed"`
+	Untracked int       `json:"untracked"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// Ref returns the ref keeping the entry's commit alive
+func (e *TrashEntry) Ref() string {
+	return TrashRefPrefix + e.ID
+}
+
+// trashDir returns the directory holding removed sessions
+func trashDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "trash"), nil
+}
+
+// trashPath returns the trash of the manager's repository
+func (m *Manager) trashPath() (string, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, m.repoID), nil
+}
+
+// saveToTrash saves everything needed to rebuild a session before it is
+// removed: its commit as a ref, its uncommitted changes as patches, its
+// untracked files as a tarball and its metadata. Ignored files are not
+// saved. It returns nil when there is nothing to save, because neither the
+// worktree nor the branch exists any more.
+func (m *Manager) saveToTrash(rec state.Session, deleteBranch bool) (*TrashEntry, error) {
+	_, statErr := os.Stat(rec.Path)
+	hasWorktree := statErr == nil
+
+	var head string
+	var err error
+	if hasWorktree {
+		head, err = git.NewBranchManager(m.git, rec.Path).ResolveCommit("HEAD")
+	} else if rec.Branch != "" {
+		head, err = m.branchManager.ResolveCommit(rec.Branch)
+	}
+	if head == "" || err != nil {
+		return nil, nil
+	}
+
+	root, err := m.trashPath()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id := rec.Name + "-" + now.UTC().Format("20060102-150405")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(root, id)); os.IsNotExist(err) {
+			break
+		}
+		id = rec.Name + "-" + now.UTC().Format("20060102-150405") + "-" + strconv.Itoa(n)
+	}
+
+	entry := &TrashEntry{ID: id, Session: rec, Head: head, BranchDeleted: deleteBranch && rec.Branch != "", RemovedAt: now}
+	if entry.BranchDeleted {
+		entry.Remote, entry.Merge = m.branchManager.Tracking(rec.Branch)
+	}
+
+	dir := filepath.Join(root, id)
+	err = m.change("save session "+rec.Name+" to the trash at "+dir, func() error {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		if err := m.fillTrashEntry(entry, dir, hasWorktree); err != nil {
+			_ = m.discardTrash(entry)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (m *Manager) fillTrashEntry(entry *TrashEntry, dir string, hasWorktree bool) error {
+	if hasWorktree {
+		path := entry.Session.Path
+		for _, patch := range []struct {
+			file   string
+			staged bool
+		}{{stagedPatchFile, true}, {unstagedPatchFile, false}} {
+			diff, err := m.git.Diff(path, patch.staged)
+			if err != nil {
+				return err
+			}
+			if len(diff) > 0 {
+				if err := os.WriteFile(filepath.Join(dir, patch.file), diff, 0600); err != nil {
+					return err
+				}
+			}
+		}
+
+		changed, _, err := m.git.UncommittedFiles(path)
+		if err != nil {
+			return err
+		}
+		entry.Changed = len(changed)
+
+		untracked, err := m.git.UntrackedFiles(path)
+		if err != nil {
+			return err
+		}
+		if len(untracked) > 0 {
+			if err := writeTar(filepath.Join(dir, untrackedFile), path, untracked); err != nil {
+				return errors.Wrap(err, "failed to save untracked files")
+			}
+		}
+		entry.Untracked = len(untracked)
+	}
+
+	if err := m.branchManager.UpdateRef(entry.Ref(), entry.Head); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(dir, trashEntryFile), data)
+}
+
+// discardTrash deletes a trash entry and its ref
+func (m *Manager) discardTrash(entry *TrashEntry) error {
+	if m.branchManager.RefExists(entry.Ref()) {
+		if err := m.branchManager.DeleteRef(entry.Ref()); err != nil {
+			return err
+		}
+	}
+	root, err := m.trashPath()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(root, entry.ID)
+	return m.change("rm -rf "+dir, func() error {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		// Drop the repository's trash along with its last entry
+		_ = os.Remove(root)
+		return nil
+	})
+}
+
+// Trash lists the repository's removed sessions, most recently removed
+// first
+func (m *Manager) Trash() ([]TrashEntry, error) {
+	root, err := m.trashPath()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TrashEntry
+	for _, d := range dirs {
+		data, err := os.ReadFile(filepath.Join(root, d.Name(), trashEntryFile)) // #nosec G304
+		if err != nil {
+			continue
+		}
+		var entry TrashEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.ID != d.Name() {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].RemovedAt.After(entries[j].RemovedAt) })
+	return entries, nil
+}
+
+// FindInTrash looks up a removed session by its trash ID or, picking the
+// most recently removed, by its name
+func (m *Manager) FindInTrash(ref string) (*TrashEntry, error) {
+	entries, err := m.Trash()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == ref {
+			return &entries[i], nil
+		}
+	}
+	for i := range entries {
+		if entries[i].Session.Name == ref {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s is not in the trash", errors.ErrSessionNotFound, ref)
+}
+
+// RestoreFromTrash rebuilds a removed session as it was: its branch, its
+// worktree at the same path with the same staged, unstaged and untracked
+// changes, and its metadata. Like CreateSession, it is undone completely
+// if any step fails. The entry leaves the trash once restored.
+func (m *Manager) RestoreFromTrash(id string) (*Result, error) {
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	entry, err := m.FindInTrash(id)
+	if err != nil {
+		return nil, err
+	}
+	rec := entry.Session
+
+	if _, err := os.Stat(rec.Path); err == nil {
+		return nil, fmt.Errorf("%w: %s", errors.ErrWorktreeExists, rec.Path)
+	}
+	// Check out the branch, or the commit itself if the session had none
+	checkout := entry.Head
+	if rec.Branch != "" {
+		checkout = rec.Branch
+		if m.branchManager.Exists(rec.Branch) {
+			if commit, err := m.branchManager.ResolveCommit(rec.Branch); err != nil || commit != entry.Head {
+				return nil, fmt.Errorf("%w: %s has moved on since %s was removed", errors.ErrBranchExists, rec.Branch, rec.Name)
+			}
+		}
+	}
+
+	tx, err := m.begin("restore", rec.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if rec.Branch != "" && !m.branchManager.Exists(rec.Branch) {
+		err := tx.step(undoAction{Kind: undoDeleteBranch, Branch: rec.Branch, Commit: entry.Head}, func() error {
+			if err := m.branchManager.Create(rec.Branch, entry.Head); err != nil {
+				return err
+			}
+			if entry.Remote != "" && entry.Merge != "" {
+				return m.branchManager.SetTracking(rec.Branch, entry.Remote, entry.Merge)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, tx.fail(err)
+		}
+	}
+
+	if err := tx.mkdirAll(filepath.Dir(rec.Path)); err != nil {
+		return nil, tx.fail(err)
+	}
+	err = tx.step(undoAction{Kind: undoRemoveWorktree, Path: rec.Path}, func() error {
+		return m.worktreeManager.Create(rec.Path, checkout)
+	})
+	if err != nil {
+		return nil, tx.fail(err)
+	}
+
+	// Whatever is put back goes with the worktree if a later step fails
+	root, err := m.trashPath()
+	if err != nil {
+		return nil, tx.fail(err)
+	}
+	err = tx.run(func() error {
+		return m.change("restore uncommitted changes into "+rec.Path, func() error {
+			return m.restoreChanges(filepath.Join(root, entry.ID), rec.Path)
+		})
+	})
+	if err != nil {
+		return nil, tx.fail(errors.Wrap(err, "failed to restore uncommitted changes"))
+	}
+
+	if err := tx.step(m.recordUndo(rec.Name), func() error { return m.recordSession(rec) }); err != nil {
+		return nil, tx.fail(err)
+	}
+	if err := tx.commit(); err != nil {
+		return nil, tx.fail(err)
+	}
+
+	if err := m.discardTrash(entry); err != nil {
+		return nil, errors.Wrap(err, "restored, but failed to empty the trash entry")
+	}
+
+	release()
+	hookCtx := hooks.Context{Session: rec.Name, Branch: rec.Branch, RepoRoot: m.mainRepoPath, Worktree: rec.Path}
+	m.runPostHooks(hooks.PostCreate, rec.Path, hookCtx)
+
+	return &Result{Session: git.SessionInfo{
+		Name:           rec.Name,
+		Branch:         rec.Branch,
+		Path:           rec.Path,
+		Description:    rec.Description,
+		BaseBranch:     rec.BaseBranch,
+		BaseCommit:     rec.BaseCommit,
+		CreatedAt:      rec.CreatedAt,
+		LastSwitchedAt: rec.LastSwitchedAt,
+	}}, nil
+}
+
+// PurgeTrash deletes the trash entries removed at least olderThan ago, all
+// of them for zero, and returns them
+func (m *Manager) PurgeTrash(olderThan time.Duration) ([]TrashEntry, error) {
+	release, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	entries, err := m.Trash()
+	if err != nil {
+		return nil, err
+	}
+	var purged []TrashEntry
+	for i := range entries {
+		if time.Since(entries[i].RemovedAt) < olderThan {
+			continue
+		}
+		if err := m.discardTrash(&entries[i]); err != nil {
+			return purged, err
+		}
+		purged = append(purged, entries[i])
+	}
+	return purged, nil
+}
+
+// restoreChanges replays the changes saved in a trash entry's directory
+// onto a freshly checked out worktree: staged changes into the index and
+// the worktree, then unstaged changes and untracked files
+func (m *Manager) restoreChanges(dir, worktree string) error {
+	for _, patch := range []struct {
+		file  string
+		index bool
+	}{{stagedPatchFile, true}, {unstagedPatchFile, false}} {
+		data, err := os.ReadFile(filepath.Join(dir, patch.file)) // #nosec G304
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.git.ApplyPatch(worktree, data, patch.index); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, untrackedFile)); err == nil {
+		return extractTar(filepath.Join(dir, untrackedFile), worktree)
+	}
+	return nil
+}
+
+// writeTar archives files, given relative to dir, into dest. Symlinks are
+// archived as links; anything but regular files and symlinks is skipped.
+func writeTar(dest, dir string, files []string) (err error) {
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	tw := tar.NewWriter(out)
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !info.Mode().IsRegular():
+			continue
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(file)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			if err := copyFileTo(tw, path); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	in, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// extractTar unpacks an archive made by writeTar into dir, refusing
+// entries that would land outside it or overwrite existing files
+func extractTar(src, dir string) error {
+	in, err := os.Open(src) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(filepath.FromSlash(header.Name)) {
+			return fmt.Errorf("refusing to extract %s outside the worktree", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, header.FileInfo().Mode().Perm()) // #nosec G304
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr) // #nosec G110 -- an archive ccswitch wrote itself
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashRestoresRemovedSession(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	writeTestFile(t, filepath.Join(repo, "app.txt"), "v1\n")
+	runGit(t, repo, "add", "app.txt")
+	runGit(t, repo, "commit", "-m", "Add app")
+
+	m := NewManager(repo)
+	result, err := m.CreateSession(CreateOptions{Description: "Experiment"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+	path, branch := result.Session.Path, result.Session.Branch
+
+	// A commit of its own, a staged and an unstaged change and an untracked file
+	runGit(t, path, "commit", "--allow-empty", "-m", "Only here")
+	writeTestFile(t, filepath.Join(path, "app.txt"), "v1\nstaged\n")
+	runGit(t, path, "add", "app.txt")
+	writeTestFile(t, filepath.Join(path, "app.txt"), "v1\nstaged\nunstaged\n")
+	writeTestFile(t, filepath.Join(path, "notes", "todo.txt"), "todo")
+	status := func() string {
+		t.Helper()
+		output, err := exec.Command("git", "-C", path, "status", "--porcelain", "--untracked-files=all").Output()
+		if err != nil {
+			t.Fatalf("git status failed: %v", err)
+		}
+		return string(output)
+	}
+	before := status()
+
+	if err := m.RemoveSession(path, RemoveOptions{Branch: branch, DeleteBranch: true, Force: true}); err != nil {
+		t.Fatalf("RemoveSession() failed: %v", err)
+	}
+	entries, err := m.Trash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Trash() = %v, %v; expected the removed session", entries, err)
+	}
+	entry := entries[0]
+	if !entry.BranchDeleted || entry.Changed != 1 || entry.Untracked != 1 || !m.branchManager.RefExists(entry.Ref()) {
+		t.Errorf("trash entry = %+v, expected a deleted branch, 1 changed and 1 untracked file, and a ref", entry)
+	}
+
+	if _, err := m.RestoreFromTrash("experiment"); err != nil {
+		t.Fatalf("RestoreFromTrash() failed: %v", err)
+	}
+	if after := status(); after != before {
+		t.Errorf("restored status:\n%s\nexpected:\n%s", after, before)
+	}
+	staged, _ := exec.Command("git", "-C", path, "diff", "--cached").Output()
+	if !strings.Contains(string(staged), "+staged") || strings.Contains(string(staged), "+unstaged") {
+		t.Errorf("the index was not restored as it was:\n%s", staged)
+	}
+	if commit, err := m.branchManager.ResolveCommit(branch); err != nil || commit != entry.Head {
+		t.Errorf("branch %s = %s, %v; expected %s", branch, commit, err, entry.Head)
+	}
+	if entries, _ := m.Trash(); len(entries) != 0 || m.branchManager.RefExists(entry.Ref()) {
+		t.Error("a restored session should leave the trash")
+	}
+
+	// Purging forgets the session and lets git collect its commit
+	if err := m.RemoveSession(path, RemoveOptions{Branch: branch, DeleteBranch: true, Force: true}); err != nil {
+		t.Fatalf("RemoveSession() failed: %v", err)
+	}
+	if purged, err := m.PurgeTrash(time.Hour); err != nil || len(purged) != 0 {
+		t.Errorf("PurgeTrash(1h) = %v, %v; expected nothing that old", purged, err)
+	}
+	if purged, err := m.PurgeTrash(0); err != nil || len(purged) != 1 {
+		t.Errorf("PurgeTrash(0) = %v, %v; expected the removed session", purged, err)
+	}
+	if _, err := m.RestoreFromTrash("experiment"); err == nil {
+		t.Error("a purged session should not be restorable")
+	}
+}
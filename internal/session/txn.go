@@ -0,0 +1,400 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+	"github.com/ksred/ccswitch/internal/state"
+	"github.com/ksred/ccswitch/internal/ui"
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// Kinds of compensating action. Actions are plain data rather than closures
+// so that an interrupted operation can be reverted by the next ccswitch run.
+const (
+	undoDeleteBranch   = "delete_branch"
+	undoRenameBranch   = "rename_branch"
+	undoRemoveWorktree = "remove_worktree"
+	undoMoveWorktree   = "move_worktree"
+	undoRemoveDir      = "remove_dir"
+	undoRestoreRecord  = "restore_record"
+)
+
+// undoAction reverses one step of a transaction. Actions are journaled
+// before their step runs, so each must be safe to apply when the step never
+// happened or only half happened.
+type undoAction struct {
+	Kind string `json:"kind"`
+	// Branch to delete, only while it still points at Commit
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	// From and To rename a branch or move a worktree back
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Path of a worktree or directory to remove
+	Path string `json:"path,omitempty"`
+	// Name and Record restore session metadata; a nil Record deletes it
+	Name   string         `json:"name,omitempty"`
+	Record *state.Session `json:"record,omitempty"`
+}
+
+// journal is the on-disk record of a transaction in progress
+type journal struct {
+	Operation string       `json:"operation"`
+	Session   string       `json:"session"`
+	PID       int          `json:"pid"`
+	StartedAt time.Time    `json:"started_at"`
+	Undo      []undoAction `json:"undo"`
+}
+
+// transaction runs the steps of an operation, undoing every completed step
+// if a later one fails or the user presses Ctrl+C. It must only be used
+// while holding the repository lock.
+type transaction struct {
+	m           *Manager
+	path        string
+	journal     journal
+	signals     chan os.Signal
+	interrupted atomic.Bool
+	// client is the manager's git client from before the transaction, whose
+	// context Ctrl+C does not cancel, so rolling back still works
+	client git.Client
+	cancel context.CancelFunc
+}
+
+// journalDir returns the directory holding journals of operations in progress
+func journalDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "journal"), nil
+}
+
+// journalPath returns the journal file for the manager's repository
+func (m *Manager) journalPath() (string, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, m.repoID+".json"), nil
+}
+
+// begin starts a transaction. Until it is committed or rolled back, Ctrl+C
+// no longer kills ccswitch; it cancels the git command running and stops
+// the transaction at the next step.
+func (m *Manager) begin(operation, session string) (*transaction, error) {
+	path, err := m.journalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &transaction{
+		m:    m,
+		path: path,
+		journal: journal{
+			Operation: operation,
+			Session:   session,
+			PID:       os.Getpid(),
+			StartedAt: time.Now(),
+		},
+	}
+	if err := tx.save(); err != nil {
+		return nil, errors.Wrap(err, "failed to write journal")
+	}
+
+	ctx, cancel := context.WithCancel(m.git.Context())
+	tx.client, tx.cancel = m.git, cancel
+	m.useClient(m.git.WithContext(ctx))
+
+	tx.signals = make(chan os.Signal, 1)
+	signal.Notify(tx.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for range tx.signals {
+			tx.interrupted.Store(true)
+			cancel()
+		}
+	}()
+
+	return tx, nil
+}
+
+// run runs a step that has nothing to undo
+func (tx *transaction) run(fn func() error) error {
+	if tx.interrupted.Load() {
+		return errors.ErrInterrupted
+	}
+	return fn()
+}
+
+// step journals undo and then runs fn
+func (tx *transaction) step(undo undoAction, fn func() error) error {
+	if tx.interrupted.Load() {
+		return errors.ErrInterrupted
+	}
+	tx.journal.Undo = append(tx.journal.Undo, undo)
+	if err := tx.save(); err != nil {
+		return errors.Wrap(err, "failed to write journal")
+	}
+	return fn()
+}
+
+// mkdirAll creates dir and any missing parents, each as its own step so
+// only directories this transaction created are removed again
+func (tx *transaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append(missing, d)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		err := tx.step(undoAction{Kind: undoRemoveDir, Path: d}, func() error {
+			return tx.m.change("mkdir "+d, func() error {
+				if err := os.Mkdir(d, 0755); err != nil && !os.IsExist(err) {
+					return err
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to create worktree directory")
+		}
+	}
+	return nil
+}
+
+// commit finishes the transaction, keeping all of its steps
+func (tx *transaction) commit() error {
+	if err := tx.run(tx.remove); err != nil {
+		return err
+	}
+	tx.stop()
+	return nil
+}
+
+// fail rolls the transaction back and returns the error that caused it
+func (tx *transaction) fail(cause error) error {
+	if tx.interrupted.Load() && !errors.IsInterrupted(cause) {
+		// The step failed because Ctrl+C cancelled it
+		cause = fmt.Errorf("%w: %v", errors.ErrInterrupted, cause)
+	}
+
+	tx.m.useClient(tx.client)
+	var err error
+	if !tx.m.IsDryRun() {
+		// A dry run made no changes to undo
+		err = tx.m.undo(tx.journal.Undo)
+	}
+	if err == nil {
+		_ = tx.remove()
+	}
+	// Otherwise the journal stays, so the next run tries the undo again
+	tx.stop()
+
+	if err != nil {
+		return fmt.Errorf("%w (%w: %v)", cause, errors.ErrRollbackIncomplete, err)
+	}
+	return cause
+}
+
+func (tx *transaction) stop() {
+	signal.Stop(tx.signals)
+	close(tx.signals)
+	tx.cancel()
+	tx.m.useClient(tx.client)
+}
+
+// save writes the journal. A dry run keeps none; it has nothing to undo.
+func (tx *transaction) save() error {
+	if tx.m.IsDryRun() {
+		return nil
+	}
+	data, err := json.MarshalIndent(tx.journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(tx.path, data)
+}
+
+func (tx *transaction) remove() error {
+	if tx.m.IsDryRun() {
+		return nil
+	}
+	return os.Remove(tx.path)
+}
+
+// recoverJournal reverts an operation a previous ccswitch run left
+// unfinished, for example because it was killed. It must only be called
+// while holding the repository lock, which guarantees the run is over.
+func (m *Manager) recoverJournal() error {
+	path, err := m.journalPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path) // #nosec G304
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("discarded unreadable journal %s: %w", path, err)
+	}
+
+	if m.IsDryRun() {
+		ui.Warningf("⚠️  An interrupted %s of session %s (pid %d) will be reverted", j.Operation, j.Session, j.PID)
+		return nil
+	}
+	ui.Warningf("⚠️  Reverting interrupted %s of session %s (pid %d)", j.Operation, j.Session, j.PID)
+	err = m.undo(j.Undo)
+	_ = os.Remove(path)
+	return err
+}
+
+// undo applies actions in reverse order, carrying on past failures so as
+// much as possible is reverted. It returns the first failure.
+func (m *Manager) undo(actions []undoAction) error {
+	var firstErr error
+	for i := len(actions) - 1; i >= 0; i-- {
+		if err := m.applyUndo(actions[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *Manager) applyUndo(a undoAction) error {
+	switch a.Kind {
+	case undoDeleteBranch:
+		if !m.branchManager.Exists(a.Branch) {
+			return nil
+		}
+		if a.Commit != "" {
+			if commit, err := m.branchManager.ResolveCommit(a.Branch); err != nil || commit != a.Commit {
+				return nil
+			}
+		}
+		return m.branchManager.Delete(a.Branch, true)
+
+	case undoRenameBranch:
+		if m.branchManager.Exists(a.From) && !m.branchManager.Exists(a.To) {
+			return m.branchManager.Rename(a.From, a.To)
+		}
+		return nil
+
+	case undoRemoveWorktree:
+		_ = m.worktreeManager.Remove(a.Path, true)
+		if err := m.removeWorktreeDir(a.Path); err != nil {
+			return err
+		}
+		return m.worktreeManager.Prune()
+
+	case undoMoveWorktree:
+		if _, err := os.Stat(a.From); err != nil {
+			return nil
+		}
+		if _, err := os.Stat(a.To); err == nil {
+			return nil
+		}
+		return m.worktreeManager.Move(a.From, a.To)
+
+	case undoRemoveDir:
+		// Only ever remove the directory if nothing else has moved in
+		err := m.change("rmdir "+a.Path, func() error { return os.Remove(a.Path) })
+		if err != nil && !os.IsNotExist(err) {
+			if entries, readErr := os.ReadDir(a.Path); readErr == nil && len(entries) > 0 {
+				return nil
+			}
+			return err
+		}
+		return nil
+
+	case undoRestoreRecord:
+		if m.store == nil {
+			return nil
+		}
+		if a.Record == nil {
+			return m.change("forget session "+a.Name, func() error { return m.store.Delete(a.Name) })
+		}
+		return m.change("record session "+a.Name, func() error { return m.store.Put(*a.Record) })
+
+	default:
+		return fmt.Errorf("unknown undo action %q", a.Kind)
+	}
+}
+
+// removeWorktreeDir removes what git left of a worktree it could not remove.
+// Like doctor, it refuses anything but a directory in the worktree storage
+// or where this repository's layout puts sessions, so a damaged journal
+// can't delete anything else.
+func (m *Manager) removeWorktreeDir(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to remove %s, which is not a directory", path)
+	}
+	if !m.isWorktreeStorage(path) {
+		return fmt.Errorf("refusing to remove %s, which is outside the worktree storage", path)
+	}
+	return m.change("rm -rf "+path, func() error { return os.RemoveAll(path) })
+}
+
+// isWorktreeStorage reports whether path is somewhere ccswitch creates
+// session worktrees, and never the main repository
+func (m *Manager) isWorktreeStorage(path string) bool {
+	resolved := canonicalPath(path)
+	if resolved == canonicalPath(m.mainRepoPath) {
+		return false
+	}
+	if root, err := layout.Root(); err == nil {
+		rel, err := filepath.Rel(canonicalPath(root), resolved)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	_, ok := m.layout.SessionName(path)
+	return ok
+}
+
+// canonicalPath resolves symlinks so paths can be compared
+func canonicalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// recordUndo captures a session's current metadata so it can be restored
+func (m *Manager) recordUndo(name string) undoAction {
+	a := undoAction{Kind: undoRestoreRecord, Name: name}
+	if m.store != nil {
+		if rec, ok, err := m.store.Get(name); err == nil && ok {
+			a.Record = &rec
+		}
+	}
+	return a
+}
@@ -0,0 +1,312 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ksred/ccswitch/internal/errors"
+	"github.com/ksred/ccswitch/internal/git"
+	"github.com/ksred/ccswitch/internal/layout"
+)
+
+// assertPristine checks that nothing of a session is left behind
+func assertPristine(t *testing.T, m *Manager, branch, session string) {
+	t.Helper()
+
+	if m.branchManager.Exists(branch) {
+		t.Errorf("branch %s was left behind", branch)
+	}
+
+	root, _ := layout.Root()
+	if _, err := os.Stat(filepath.Join(root, m.RepoID())); !os.IsNotExist(err) {
+		t.Errorf("worktree directory %s was left behind", filepath.Join(root, m.RepoID()))
+	}
+
+	output, err := exec.Command("git", "-C", m.mainRepoPath, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		t.Fatalf("git worktree list failed: %v", err)
+	}
+	if n := strings.Count(string(output), "worktree "); n != 1 {
+		t.Errorf("found %d worktrees, expected only the main repository:\n%s", n, output)
+	}
+
+	if _, ok, _ := m.store.Get(session); ok {
+		t.Errorf("metadata for %s was left behind", session)
+	}
+
+	path, _ := m.journalPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("journal was left behind")
+	}
+}
+
+// failingRunner runs git for real, except commands starting with fail
+type failingRunner struct {
+	git.ExecRunner
+	fail string
+}
+
+func (r failingRunner) Run(ctx context.Context, c git.Command) ([]byte, error) {
+	if strings.HasPrefix(strings.Join(c.Args, " "), r.fail) {
+		return nil, git.NewCommandError(c, 128, "fatal: injected failure", fmt.Errorf("exit status 128"))
+	}
+	return r.ExecRunner.Run(ctx, c)
+}
+
+func TestCreateSessionRollsBackOnFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	// Carrying files over is the last step before the metadata is written
+	m := NewManager(repo)
+	m.config.Worktree.CarryOver.Mode = "hardlink"
+
+	if _, err := m.CreateSession(CreateOptions{Description: "Doomed"}); err == nil {
+		t.Fatal("CreateSession() should fail with an invalid carry_over mode")
+	}
+	assertPristine(t, m, "feature/doomed", "doomed")
+}
+
+func TestCreateSessionRollsBackOnInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh")
+	}
+
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	// Press Ctrl+C while the pre_create hook runs
+	m := NewManager(repo)
+	m.config.Hooks.PreCreate = []string{"kill -INT $PPID; sleep 0.2"}
+
+	_, err := m.CreateSession(CreateOptions{Description: "Interrupted"})
+	if !errors.IsInterrupted(err) {
+		t.Fatalf("CreateSession() error = %v, expected ErrInterrupted", err)
+	}
+	assertPristine(t, m, "feature/interrupted", "interrupted")
+}
+
+func TestRecoverInterruptedTransaction(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	// A run that dies after creating the branch and worktree
+	m := NewManager(repo)
+	release, err := m.lock()
+	if err != nil {
+		t.Fatalf("lock() failed: %v", err)
+	}
+	tx, err := m.begin("create", "half-built")
+	if err != nil {
+		t.Fatalf("begin() failed: %v", err)
+	}
+	path := m.GetSessionPath("half-built")
+	if err := tx.mkdirAll(filepath.Dir(path)); err != nil {
+		t.Fatalf("mkdirAll() failed: %v", err)
+	}
+	err = tx.step(undoAction{Kind: undoDeleteBranch, Branch: "feature/half-built"}, func() error {
+		return m.branchManager.Create("feature/half-built", "")
+	})
+	if err != nil {
+		t.Fatalf("step() failed: %v", err)
+	}
+	err = tx.step(undoAction{Kind: undoRemoveWorktree, Path: path}, func() error {
+		return m.worktreeManager.Create(path, "feature/half-built")
+	})
+	if err != nil {
+		t.Fatalf("step() failed: %v", err)
+	}
+	tx.stop()
+	release()
+
+	// The next run reverts it as soon as it takes the lock
+	next := NewManager(repo)
+	release, err = next.lock()
+	if err != nil {
+		t.Fatalf("lock() failed: %v", err)
+	}
+	release()
+
+	assertPristine(t, next, "feature/half-built", "half-built")
+}
+
+func TestRenameSessionRollsBackOnFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+	created, err := NewManager(repo).CreateSession(CreateOptions{Description: "Old name"})
+	if err != nil {
+		t.Fatalf("CreateSession() failed: %v", err)
+	}
+
+	// The branch is renamed before the worktree move fails
+	client := git.NewClient(context.Background(), failingRunner{fail: "worktree move"})
+	m := NewManagerWithClient(client, repo)
+	if _, err := m.RenameSession("old-name", "New name"); err == nil {
+		t.Fatal("RenameSession() should fail when the worktree can't be moved")
+	}
+
+	if !m.branchManager.Exists("feature/old-name") || m.branchManager.Exists("feature/new-name") {
+		t.Error("the branch rename was not undone")
+	}
+	if _, err := os.Stat(created.Session.Path); err != nil {
+		t.Errorf("the worktree left %s: %v", created.Session.Path, err)
+	}
+	if rec, ok, _ := m.store.Get("old-name"); !ok || rec.Branch != "feature/old-name" {
+		t.Errorf("session record = %+v, %v; expected it unchanged", rec, ok)
+	}
+	if _, ok, _ := m.store.Get("new-name"); ok {
+		t.Error("a record for the new name was left behind")
+	}
+	path, _ := m.journalPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("journal was left behind")
+	}
+}
+
+func TestIncompleteRollback(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	repo := filepath.Join(tempDir, "api")
+	initTestRepo(t, repo)
+
+	// A journal pointing outside the storage must never delete anything there
+	outside := filepath.Join(tempDir, "precious")
+	writeTestFile(t, filepath.Join(outside, "notes.txt"), "keep me")
+
+	m := NewManager(repo)
+	release, err := m.lock()
+	if err != nil {
+		t.Fatalf("lock() failed: %v", err)
+	}
+	defer release()
+	tx, err := m.begin("create", "stray")
+	if err != nil {
+		t.Fatalf("begin() failed: %v", err)
+	}
+	_ = tx.step(undoAction{Kind: undoRemoveWorktree, Path: outside}, func() error { return nil })
+	err = tx.fail(errors.ErrInterrupted)
+
+	if !errors.IsRollbackIncomplete(err) {
+		t.Fatalf("fail() = %v, expected ErrRollbackIncomplete", err)
+	}
+	if hint := errors.ErrorHint(err); strings.Contains(hint, "rolled back") {
+		t.Errorf("ErrorHint() = %q, claims everything was rolled back", hint)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "notes.txt")); err != nil {
+		t.Errorf("undo removed %s outside the worktree storage: %v", outside, err)
+	}
+	path, _ := m.journalPath()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the journal should be kept so the undo is retried: %v", err)
+	}
+}
+
+// newFakeManager creates a manager for the repository /repos/api whose git
+// commands are all answered by fake
+func newFakeManager(fake *git.FakeRunner) *Manager {
+	fake.On("rev-parse --git-common-dir", "/repos/api/.git\n")
+	return NewManagerWithClient(git.NewClient(context.Background(), fake), "/repos/api")
+}
+
+func TestCreateSessionRollsBackWithFakeGit(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	fake := git.NewFakeRunner().
+		On("rev-parse --verify main^{commit}", "abc123\n").
+		// The branch is missing until it is created
+		Fail("rev-parse --verify refs/heads/feature/fix-bug", 128, "fatal: Needed a single revision").
+		On("rev-parse --verify refs/heads/feature/fix-bug", "abc123\n").
+		On("rev-parse --verify feature/fix-bug^{commit}", "abc123\n").
+		On("branch --no-track feature/fix-bug abc123", "").
+		On("branch -D feature/fix-bug", "").
+		On("worktree prune", "")
+	m := newFakeManager(fake)
+	path := m.GetSessionPath("fix-bug")
+	fake.Fail("worktree add "+path+" feature/fix-bug", 128, "Preparing worktree (checking out 'feature/fix-bug')\nfatal: '"+path+"' already exists")
+
+	_, err := m.CreateSession(CreateOptions{Description: "Fix bug", From: "main"})
+	if !errors.IsWorktreeExists(err) {
+		t.Fatalf("CreateSession() = %v, expected git's failure classified as ErrWorktreeExists", err)
+	}
+	if errors.IsRollbackIncomplete(err) {
+		t.Errorf("CreateSession() = %v, expected a complete rollback", err)
+	}
+	if !fake.Ran("branch -D feature/fix-bug") {
+		t.Error("the branch created before the worktree failed was not deleted")
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("worktree directory %s was left behind", filepath.Dir(path))
+	}
+	if m.store.Exists() {
+		t.Error("the session was recorded")
+	}
+	journal, _ := m.journalPath()
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Error("journal was left behind")
+	}
+}
+
+func TestRenameSessionRollsBackWithFakeGit(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	fake := git.NewFakeRunner()
+	m := newFakeManager(fake)
+	oldPath, newPath := m.GetSessionPath("old-name"), m.GetSessionPath("new-name")
+	fake.On("worktree list --porcelain", "worktree /repos/api\nHEAD abc123\nbranch refs/heads/main\n\n"+
+		"worktree "+oldPath+"\nHEAD abc123\nbranch refs/heads/feature/old-name\n").
+		// The new branch is missing until the rename creates it
+		Fail("rev-parse --verify refs/heads/feature/new-name", 128, "fatal: Needed a single revision").
+		On("rev-parse --verify refs/heads/feature/new-name", "abc123\n").
+		On("branch -m feature/old-name feature/new-name", "").
+		On("branch -m feature/new-name feature/old-name", "").
+		Fail("worktree move "+oldPath+" "+newPath, 128, "fatal: cannot move a locked working tree, lock reason: in use\nuse 'move -f -f' to override or unlock first")
+
+	_, err := m.RenameSession("old-name", "New name")
+	if !errors.IsWorktreeLocked(err) {
+		t.Fatalf("RenameSession() = %v, expected git's failure classified as ErrWorktreeLocked", err)
+	}
+	if errors.ExitCode(err) != errors.ExitLocked {
+		t.Errorf("ExitCode() = %d, expected %d", errors.ExitCode(err), errors.ExitLocked)
+	}
+	if !fake.Ran("branch -m feature/new-name feature/old-name") {
+		t.Error("the branch rename was not undone")
+	}
+	if _, err := os.Stat(filepath.Dir(newPath)); !os.IsNotExist(err) {
+		t.Errorf("worktree directory %s was left behind", filepath.Dir(newPath))
+	}
+}
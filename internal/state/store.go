@@ -0,0 +1,197 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/utils"
+)
+
+// SchemaVersion is the current version of the on-disk state format
+const SchemaVersion = 1
+
+// Session holds the metadata ccswitch keeps about a session that
+// cannot be recovered from git itself
+type Session struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	// BranchNamed records whether ccswitch named the branch after the
+	// session, so renaming the session renames the branch too. It is nil in
+	// records written before it was kept.
+	BranchNamed    *bool     `json:"branch_named,omitempty"`
+	Path           string    `json:"path"`
+	Description    string    `json:"description,omitempty"`
+	BaseBranch     string    `json:"base_branch,omitempty"`
+	BaseCommit     string    `json:"base_commit,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastSwitchedAt time.Time `json:"last_switched_at"`
+}
+
+// File is the on-disk representation of a repository's state
+type File struct {
+	Version  int                 `json:"version"`
+	Repo     string              `json:"repo"`
+	Sessions map[string]*Session `json:"sessions"`
+}
+
+// Store reads and writes the state file for a single repository
+type Store struct {
+	path string
+	repo string
+}
+
+// Dir returns the directory holding all state files
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ccswitch", "state"), nil
+}
+
+// NewStore creates a store for the given repository ID (see git.GetRepoID).
+// Older versions keyed state files by repository name.
+func NewStore(repoID string) (*Store, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: filepath.Join(dir, repoID+".json"), repo: repoID}, nil
+}
+
+// Path returns the location of the state file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Exists reports whether the state file has been written
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Remove deletes the state file
+func (s *Store) Remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Load reads the state file, returning an empty state if it does not exist
+func (s *Store) Load() (*File, error) {
+	f := &File{Version: SchemaVersion, Repo: s.repo, Sessions: map[string]*Session{}}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	if f.Version > SchemaVersion {
+		return nil, fmt.Errorf("state file %s has version %d, this ccswitch supports up to %d", s.path, f.Version, SchemaVersion)
+	}
+	if f.Sessions == nil {
+		f.Sessions = map[string]*Session{}
+	}
+	f.Version = SchemaVersion
+
+	return f, nil
+}
+
+// Save writes the state file atomically
+func (s *Store) Save(f *File) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.path, data)
+}
+
+// List returns all recorded sessions sorted by name
+func (s *Store) List() ([]Session, error) {
+	f, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(f.Sessions))
+	for _, rec := range f.Sessions {
+		sessions = append(sessions, *rec)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Name < sessions[j].Name
+	})
+
+	return sessions, nil
+}
+
+// Get returns the recorded session with the given name
+func (s *Store) Get(name string) (Session, bool, error) {
+	f, err := s.Load()
+	if err != nil {
+		return Session{}, false, err
+	}
+	rec, ok := f.Sessions[name]
+	if !ok {
+		return Session{}, false, nil
+	}
+	return *rec, true, nil
+}
+
+// Put records a session, replacing any existing record with the same name
+func (s *Store) Put(rec Session) error {
+	f, err := s.Load()
+	if err != nil {
+		return err
+	}
+	f.Sessions[rec.Name] = &rec
+	return s.Save(f)
+}
+
+// Update applies fn to the named session, creating the record if needed
+func (s *Store) Update(name string, fn func(*Session)) error {
+	f, err := s.Load()
+	if err != nil {
+		return err
+	}
+	rec, ok := f.Sessions[name]
+	if !ok {
+		rec = &Session{Name: name}
+		f.Sessions[name] = rec
+	}
+	fn(rec)
+	return s.Save(f)
+}
+
+// Delete removes the named session record
+func (s *Store) Delete(name string) error {
+	f, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Sessions[name]; !ok {
+		return nil
+	}
+	delete(f.Sessions, name)
+	return s.Save(f)
+}
+
+// FindByPath returns the recorded session whose worktree lives at path
+func (f *File) FindByPath(path string) *Session {
+	for _, rec := range f.Sessions {
+		if rec.Path == path {
+			return rec
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	store, err := NewStore("myrepo")
+	if err != nil {
+		t.Fatalf("NewStore() failed: %v", err)
+	}
+
+	expectedPath := filepath.Join(tempDir, ".ccswitch", "state", "myrepo.json")
+	if store.Path() != expectedPath {
+		t.Errorf("Store.Path() = %q, expected %q", store.Path(), expectedPath)
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec := Session{
+		Name:        "fix-auth-bug",
+		Branch:      "feature/fix-auth-bug",
+		Path:        "/tmp/worktrees/myrepo/fix-auth-bug",
+		Description: "Fix auth bug",
+		BaseBranch:  "main",
+		BaseCommit:  "abc123",
+		CreatedAt:   created,
+	}
+	if err := store.Put(rec); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	got, ok, err := store.Get("fix-auth-bug")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v; expected record", ok, err)
+	}
+	if got.Description != "Fix auth bug" {
+		t.Errorf("Description = %q, expected %q", got.Description, "Fix auth bug")
+	}
+	if !got.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, expected %v", got.CreatedAt, created)
+	}
+
+	f, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if f.Version != SchemaVersion {
+		t.Errorf("Version = %d, expected %d", f.Version, SchemaVersion)
+	}
+	if f.FindByPath(rec.Path) == nil {
+		t.Error("FindByPath() did not find the recorded session")
+	}
+
+	if err := store.Delete("fix-auth-bug"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, ok, _ := store.Get("fix-auth-bug"); ok {
+		t.Error("Get() found a deleted session")
+	}
+}
+
+func TestStoreUpdateCreatesRecord(t *testing.T) {
+	store := &Store{path: filepath.Join(t.TempDir(), "repo.json"), repo: "repo"}
+
+	switched := time.Now()
+	err := store.Update("new-session", func(rec *Session) {
+		rec.LastSwitchedAt = switched
+	})
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Name != "new-session" {
+		t.Fatalf("List() = %+v, expected one session named new-session", sessions)
+	}
+	if !sessions[0].LastSwitchedAt.Equal(switched) {
+		t.Errorf("LastSwitchedAt = %v, expected %v", sessions[0].LastSwitchedAt, switched)
+	}
+}
+
+func TestStoreLoadMissingFile(t *testing.T) {
+	store := &Store{path: filepath.Join(t.TempDir(), "missing.json"), repo: "missing"}
+
+	f, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() of missing file failed: %v", err)
+	}
+	if len(f.Sessions) != 0 {
+		t.Errorf("Load() of missing file returned %d sessions, expected 0", len(f.Sessions))
+	}
+}
+
+func TestStoreRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "sessions": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	store := &Store{path: path, repo: "repo"}
+	if _, err := store.Load(); err == nil {
+		t.Error("Load() should fail for a state file from a newer version")
+	}
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/ccswitch/internal/git"
+)
+
+// FormatAge describes how long ago t was, e.g. "5m ago" or "3d ago"
+func FormatAge(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 60*24*time.Hour:
+		return fmt.Sprintf("%dw ago", int(d.Hours()/24/7))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/24/365))
+	}
+}
+
+// FormatChanges describes a worktree's uncommitted files, e.g. "3 changed, 1 untracked"
+func FormatChanges(status *git.SessionStatus) string {
+	if !status.Dirty() {
+		return "clean"
+	}
+	var parts []string
+	if status.Changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", status.Changed))
+	}
+	if status.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", status.Untracked))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatAheadBehind describes commits ahead of and behind another branch,
+// e.g. "↑2 ↓1", or "" when in sync
+func FormatAheadBehind(ahead, behind int) string {
+	var parts []string
+	if ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", ahead))
+	}
+	if behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// FormatSize describes a size in bytes, e.g. "12 MB"
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes)
+	for _, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit || suffix == "TB" {
+			if value < 10 {
+				return fmt.Sprintf("%.1f %s", value, suffix)
+			}
+			return fmt.Sprintf("%.0f %s", value, suffix)
+		}
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -10,11 +11,27 @@ import (
 	"github.com/ksred/ccswitch/internal/git"
 )
 
+// StatusLoader fills in the Status of each session
+type StatusLoader func(sessions []git.SessionInfo)
+
+// statusMsg carries freshly loaded statuses back to the selector
+type statusMsg []git.SessionInfo
+
+// refreshStatusMsg asks the selector to load statuses again
+type refreshStatusMsg struct{}
+
 type SessionSelector struct {
 	sessions []git.SessionInfo
 	cursor   int
 	selected int
 	quit     bool
+
+	loadStatus      StatusLoader
+	refreshInterval time.Duration
+	statusLoaded    bool
+
+	// groups[i] is the heading session i is listed under, if any
+	groups []string
 }
 
 func NewSessionSelector(sessions []git.SessionInfo) *SessionSelector {
@@ -24,11 +41,60 @@ func NewSessionSelector(sessions []git.SessionInfo) *SessionSelector {
 	}
 }
 
+// WithStatus makes the selector show each session's git status. Statuses
+// load in the background, so the list appears immediately, and are
+// refreshed every interval while the selector is open.
+func (s *SessionSelector) WithStatus(load StatusLoader, interval time.Duration) *SessionSelector {
+	s.loadStatus = load
+	s.refreshInterval = interval
+	return s
+}
+
+// WithGroups lists sessions under headings, such as their repository.
+// groups[i] is the heading of session i; sessions sharing a heading must be
+// adjacent.
+func (s *SessionSelector) WithGroups(groups []string) *SessionSelector {
+	s.groups = groups
+	return s
+}
+
 func (s *SessionSelector) Init() tea.Cmd {
-	return nil
+	if s.loadStatus == nil {
+		return nil
+	}
+	return s.fetchStatus()
+}
+
+// fetchStatus loads statuses into a copy of the sessions, leaving the ones
+// being rendered untouched until the results arrive
+func (s *SessionSelector) fetchStatus() tea.Cmd {
+	sessions := make([]git.SessionInfo, len(s.sessions))
+	copy(sessions, s.sessions)
+	load := s.loadStatus
+	return func() tea.Msg {
+		load(sessions)
+		return statusMsg(sessions)
+	}
 }
 
 func (s *SessionSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case statusMsg:
+		s.statusLoaded = true
+		for i := range s.sessions {
+			if i < len(msg) && msg[i].Path == s.sessions[i].Path && msg[i].Status != nil {
+				s.sessions[i].Status = msg[i].Status
+			}
+		}
+		if s.refreshInterval <= 0 {
+			return s, nil
+		}
+		return s, tea.Tick(s.refreshInterval, func(time.Time) tea.Msg { return refreshStatusMsg{} })
+
+	case refreshStatusMsg:
+		return s, s.fetchStatus()
+	}
+
 	if msg, ok := msg.(tea.KeyMsg); ok {
 		switch {
 		case key.Matches(msg, key.NewBinding(key.WithKeys("q", "ctrl+c", "esc"))):
@@ -64,6 +130,13 @@ func (s *SessionSelector) View() string {
 	b.WriteString("\n\n")
 
 	for i, session := range s.sessions {
+		if i < len(s.groups) && (i == 0 || s.groups[i] != s.groups[i-1]) {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(InfoStyle.Bold(true).Render(s.groups[i]) + "\n")
+		}
+
 		cursor := "  "
 		if s.cursor == i {
 			cursor = "→ "
@@ -75,7 +148,13 @@ func (s *SessionSelector) View() string {
 		} else {
 			b.WriteString(sessionLine)
 		}
+		if session.Description != "" {
+			b.WriteString(MutedStyle.Render("  " + session.Description))
+		}
 		b.WriteString("\n")
+		if s.loadStatus != nil {
+			b.WriteString("    " + s.statusLine(session.Status) + "\n")
+		}
 	}
 
 	b.WriteString("\n")
@@ -84,6 +163,32 @@ func (s *SessionSelector) View() string {
 	return b.String()
 }
 
+// statusLine renders a session's status below its name
+func (s *SessionSelector) statusLine(status *git.SessionStatus) string {
+	if status == nil && s.statusLoaded {
+		return MutedStyle.Render("status unavailable")
+	}
+	if status == nil {
+		return MutedStyle.Render("loading status…")
+	}
+
+	changes := FormatChanges(status)
+	if status.Dirty() {
+		changes = WarningStyle.Render("● " + changes)
+	} else {
+		changes = MutedStyle.Render("✓ " + changes)
+	}
+
+	parts := []string{changes}
+	if ab := FormatAheadBehind(status.Ahead, status.Behind); ab != "" {
+		parts = append(parts, InfoStyle.Render(ab)+MutedStyle.Render(" "+status.Compare))
+	}
+	if status.LastCommitSubject != "" {
+		parts = append(parts, MutedStyle.Render(fmt.Sprintf("%s (%s)", truncate(status.LastCommitSubject, 50), FormatAge(status.LastCommitTime))))
+	}
+	return strings.Join(parts, MutedStyle.Render(" · "))
+}
+
 func (s *SessionSelector) GetSelected() *git.SessionInfo {
 	if s.selected >= 0 && s.selected < len(s.sessions) {
 		return &s.sessions[s.selected]
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// StreamWriter forwards output from child processes line by line, dimmed and
+// indented so it stands apart from ccswitch's own messages. It writes to
+// stderr to keep stdout free for the shell wrapper.
+type StreamWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+// NewStreamWriter creates a StreamWriter that prefixes every line with prefix
+func NewStreamWriter(prefix string) *StreamWriter {
+	return &StreamWriter{out: os.Stderr, prefix: prefix}
+}
+
+// Write implements io.Writer, emitting each complete line as it arrives
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line until the rest of it arrives
+			w.buf.Reset()
+			w.buf.Write(line)
+			break
+		}
+		w.writeLine(string(bytes.TrimRight(line, "\r\n")))
+	}
+	return len(p), nil
+}
+
+// Flush writes out any trailing output that did not end in a newline
+func (w *StreamWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.writeLine(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *StreamWriter) writeLine(line string) {
+	_, _ = io.WriteString(w.out, MutedStyle.Render(w.prefix+line)+"\n")
+}
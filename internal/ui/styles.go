@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"io"
+
 	"github.com/charmbracelet/lipgloss"
 	"github.com/fatih/color"
 )
@@ -11,6 +13,7 @@ var (
 	ErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Padding(0).Margin(0)
 	SuccessStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("46")).Padding(0).Margin(0)
 	WarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Padding(0).Margin(0)
+	MutedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Padding(0).Margin(0)
 
 	infoColor    = color.New(color.FgBlue)
 	successColor = color.New(color.FgGreen)
@@ -68,3 +71,9 @@ func Warningf(format string, args ...interface{}) {
 func Warning(msg string) {
 	warningColor.Println(msg)
 }
+
+// SetOutput redirects messages, e.g. to stderr while stdout carries
+// machine-readable output
+func SetOutput(w io.Writer) {
+	color.Output = w
+}
@@ -15,12 +15,12 @@ var (
 
 // Info holds version information
 type Info struct {
-	Version   string
-	Commit    string
-	BuildTime string
-	GoVersion string
-	OS        string
-	Arch      string
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit" yaml:"commit"`
+	BuildTime string `json:"build_time" yaml:"build_time"`
+	GoVersion string `json:"go_version" yaml:"go_version"`
+	OS        string `json:"os" yaml:"os"`
+	Arch      string `json:"arch" yaml:"arch"`
 }
 
 // Get returns version information
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/ksred/ccswitch/cmd"
+	"github.com/ksred/ccswitch/internal/errors"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		panic(err)
+		os.Exit(errors.ExitCode(err))
 	}
 }